@@ -2,6 +2,7 @@ package activities
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"github.com/kshitiz1403/jsonjuggler/logger"
@@ -14,6 +15,13 @@ type Activity interface {
 	Execute(ctx context.Context, arguments map[string]any) (interface{}, error)
 }
 
+// ArgumentsDeclarer is an optional interface for activities that declare the struct their arguments are parsed
+// into. The static arguments of the actions calling such an activity are checked when the workflow is parsed.
+type ArgumentsDeclarer interface {
+	// Arguments returns a pointer to a new argument struct, tagged for utils.ParseAndValidateArgs
+	Arguments() any
+}
+
 type activityRegistry struct {
 	fn   Activity
 	name string
@@ -23,6 +31,8 @@ type activityRegistry struct {
 type Registry struct {
 	sync.Mutex
 	activities map[string]*activityRegistry
+	// operations maps the operations of custom workflow functions, e.g. "jq:transform", to activity names
+	operations map[string]string
 	logger     logger.Logger
 }
 
@@ -30,6 +40,7 @@ type Registry struct {
 func NewRegistry(logger logger.Logger) *Registry {
 	return &Registry{
 		activities: make(map[string]*activityRegistry),
+		operations: make(map[string]string),
 		logger:     logger,
 	}
 }
@@ -43,6 +54,47 @@ func (r *Registry) Get(name string) (Activity, bool) {
 	return activityRegistry.fn, true
 }
 
+// RegisterOperation maps the operation of custom workflow functions, e.g. "jq:transform", to the activity that
+// runs it. The activity does not need to be registered yet.
+func (r *Registry) RegisterOperation(operation string, name string) error {
+	if existing, ok := r.operations[operation]; ok {
+		r.logger.Warnf("Operation %s is already mapped to activity %s", operation, existing)
+		return fmt.Errorf("operation %s is already mapped to activity %s", operation, existing)
+	}
+	r.operations[operation] = name
+	r.logger.Debugf("Successfully mapped operation %s to activity %s", operation, name)
+	return nil
+}
+
+// LookupOperation returns the name of the activity that runs the operation: the activity the operation is mapped
+// to, or the activity registered under the operation itself
+func (r *Registry) LookupOperation(operation string) (string, bool) {
+	if name, ok := r.operations[operation]; ok {
+		return name, true
+	}
+	if _, ok := r.activities[operation]; ok {
+		return operation, true
+	}
+	return "", false
+}
+
+// GetArguments returns a new argument struct for the activity, if it declares one through ArgumentsDeclarer
+func (r *Registry) GetArguments(name string) (any, bool) {
+	activityRegistry, ok := r.activities[name]
+	if !ok {
+		return nil, false
+	}
+	activity := activityRegistry.fn
+	if wrapper, ok := activity.(*activityWrapper); ok {
+		activity = wrapper.activity
+	}
+	declarer, ok := activity.(ArgumentsDeclarer)
+	if !ok {
+		return nil, false
+	}
+	return declarer.Arguments(), true
+}
+
 // GetLogger returns the logger for the registry
 func (r *Registry) GetLogger() logger.Logger {
 	return r.logger
@@ -24,6 +24,14 @@ const (
 
 	// JWE specific errors
 	ErrJWEEncryptError ErrorCode = "JWE_ENCRYPT_ERROR"
+	ErrJWEDecryptError ErrorCode = "JWE_DECRYPT_ERROR"
+
+	// JWS specific errors
+	ErrJWSSignError   ErrorCode = "JWS_SIGN_ERROR"
+	ErrJWSVerifyError ErrorCode = "JWS_VERIFY_ERROR"
+
+	// Key errors of the JWE and JWS activities
+	ErrKeyError ErrorCode = "KEY_ERROR"
 )
 
 // ActivityError represents a structured error from an activity
@@ -52,6 +60,11 @@ func (e *ActivityError) Error() string {
 	return msg
 }
 
+// Unwrap returns the underlying cause so the error chain can be inspected with errors.Is and errors.As
+func (e *ActivityError) Unwrap() error {
+	return e.Cause
+}
+
 // NewActivityError creates a new ActivityError
 func NewActivityError(code ErrorCode, message string, activityName string) *ActivityError {
 	return &ActivityError{
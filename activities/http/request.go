@@ -56,6 +56,11 @@ func New(activityName string, logger logger.Logger) *RequestActivity {
 	}
 }
 
+// Arguments declares the arguments of the HTTP request activity
+func (a *RequestActivity) Arguments() any {
+	return &RequestArgs{}
+}
+
 func (a *RequestActivity) Execute(ctx context.Context, arguments map[string]any) (interface{}, error) {
 	var args RequestArgs
 	if err := utils.ParseAndValidateArgs(ctx, arguments, &args); err != nil {
@@ -67,9 +72,11 @@ func (a *RequestActivity) Execute(ctx context.Context, arguments map[string]any)
 		).WithArguments(arguments).WithCause(err)
 	}
 
-	// Set timeout
+	// Set timeout. The client is shared by concurrent requests, so the timeout is set on the request's context.
 	if args.TimeoutSec > 0 {
-		a.client.Timeout = time.Duration(args.TimeoutSec) * time.Second
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(args.TimeoutSec)*time.Second)
+		defer cancel()
 	}
 
 	a.GetLogger().DebugContextf(ctx, "Making HTTP request to %s", args.URL)
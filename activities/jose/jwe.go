@@ -0,0 +1,223 @@
+package jose
+
+import (
+	"context"
+
+	gojose "github.com/go-jose/go-jose/v4"
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+)
+
+const (
+	// DefaultKeyManagementAlgorithm is the JWE key management algorithm used when none is given
+	DefaultKeyManagementAlgorithm = string(gojose.RSA_OAEP_256)
+	// DefaultContentEncryptionAlgorithm is the JWE content encryption algorithm used when none is given
+	DefaultContentEncryptionAlgorithm = string(gojose.A256GCM)
+)
+
+// EncryptArgs represents the arguments for the JWE encrypt activity
+type EncryptArgs struct {
+	Payload                    any    `arg:"payload" required:"true"` // strings are encrypted as they are, other values as JSON
+	PublicKey                  any    `arg:"publicKey"`               // JWK or PEM, used instead of the key provider
+	KeyID                      string `arg:"keyId"`
+	KeyManagementAlgorithm     string `arg:"keyManagementAlgorithm" default:"RSA-OAEP-256" validate:"oneof=RSA-OAEP RSA-OAEP-256 ECDH-ES ECDH-ES+A128KW ECDH-ES+A192KW ECDH-ES+A256KW A128KW A192KW A256KW A128GCMKW A192GCMKW A256GCMKW dir"`
+	ContentEncryptionAlgorithm string `arg:"contentEncryptionAlgorithm" default:"A256GCM" validate:"oneof=A128CBC-HS256 A192CBC-HS384 A256CBC-HS512 A128GCM A192GCM A256GCM"`
+}
+
+// DecryptArgs represents the arguments for the JWE decrypt activity
+type DecryptArgs struct {
+	Token                      string `arg:"token" required:"true"`
+	PrivateKey                 any    `arg:"privateKey"` // JWK or PEM, used instead of the key provider
+	KeyID                      string `arg:"keyId"`      // defaults to the kid header of the token
+	KeyManagementAlgorithm     string `arg:"keyManagementAlgorithm" default:"RSA-OAEP-256" validate:"oneof=RSA-OAEP RSA-OAEP-256 ECDH-ES ECDH-ES+A128KW ECDH-ES+A192KW ECDH-ES+A256KW A128KW A192KW A256KW A128GCMKW A192GCMKW A256GCMKW dir"`
+	ContentEncryptionAlgorithm string `arg:"contentEncryptionAlgorithm" default:"A256GCM" validate:"oneof=A128CBC-HS256 A192CBC-HS384 A256CBC-HS512 A128GCM A192GCM A256GCM"`
+}
+
+// EncryptActivity encrypts a payload into a JWE in compact serialization
+type EncryptActivity struct {
+	activities.BaseActivity
+	keys KeyProvider
+}
+
+// NewJWEEncrypt creates a new JWE encrypt activity. The key provider supplies the keys referenced with the keyId
+// argument, and can be nil.
+func NewJWEEncrypt(activityName string, logger logger.Logger, keys KeyProvider) *EncryptActivity {
+	return &EncryptActivity{
+		BaseActivity: activities.BaseActivity{
+			ActivityName: activityName,
+			Logger:       logger,
+		},
+		keys: keys,
+	}
+}
+
+// Arguments declares the arguments of the JWE encrypt activity
+func (a *EncryptActivity) Arguments() any {
+	return &EncryptArgs{}
+}
+
+func (a *EncryptActivity) Execute(ctx context.Context, arguments map[string]any) (interface{}, error) {
+	var args EncryptArgs
+	if err := utils.ParseAndValidateArgs(ctx, arguments, &args); err != nil {
+		a.GetLogger().ErrorContextf(ctx, "Invalid JWE encrypt arguments: %v", err)
+		return nil, activities.NewActivityError(
+			activities.ErrInvalidArguments,
+			"Invalid JWE encrypt arguments",
+			"JWEEncrypt",
+		).WithCause(err)
+	}
+	if args.KeyManagementAlgorithm == "" {
+		args.KeyManagementAlgorithm = DefaultKeyManagementAlgorithm
+	}
+	if args.ContentEncryptionAlgorithm == "" {
+		args.ContentEncryptionAlgorithm = DefaultContentEncryptionAlgorithm
+	}
+
+	key, err := resolveKey(ctx, a.keys, "publicKey", args.PublicKey, args.KeyID)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrKeyError,
+			"Failed to resolve the JWE encryption key",
+			"JWEEncrypt",
+		).WithArguments(map[string]interface{}{
+			"keyId": args.KeyID,
+		}).WithCause(err)
+	}
+
+	payload, err := encodePayload(args.Payload)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWEEncryptError,
+			"Failed to marshal JWE payload",
+			"JWEEncrypt",
+		).WithCause(err)
+	}
+
+	a.GetLogger().DebugContextf(ctx, "Encrypting JWE with %s and %s", args.KeyManagementAlgorithm, args.ContentEncryptionAlgorithm)
+
+	encrypter, err := gojose.NewEncrypter(
+		gojose.ContentEncryption(args.ContentEncryptionAlgorithm),
+		gojose.Recipient{
+			Algorithm: gojose.KeyAlgorithm(args.KeyManagementAlgorithm),
+			Key:       publicKey(key),
+			KeyID:     args.KeyID,
+		},
+		nil,
+	)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWEEncryptError,
+			"Failed to create JWE encrypter",
+			"JWEEncrypt",
+		).WithArguments(map[string]interface{}{
+			"keyManagementAlgorithm":     args.KeyManagementAlgorithm,
+			"contentEncryptionAlgorithm": args.ContentEncryptionAlgorithm,
+		}).WithCause(err)
+	}
+
+	object, err := encrypter.Encrypt(payload)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWEEncryptError,
+			"Failed to encrypt JWE payload",
+			"JWEEncrypt",
+		).WithCause(err)
+	}
+	token, err := object.CompactSerialize()
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWEEncryptError,
+			"Failed to serialize JWE",
+			"JWEEncrypt",
+		).WithCause(err)
+	}
+
+	return token, nil
+}
+
+// DecryptActivity decrypts a JWE, returning its payload decoded from JSON, or as a string if it is not JSON
+type DecryptActivity struct {
+	activities.BaseActivity
+	keys KeyProvider
+}
+
+// NewJWEDecrypt creates a new JWE decrypt activity. The key provider supplies the keys referenced with the keyId
+// argument or the kid header of the token, and can be nil.
+func NewJWEDecrypt(activityName string, logger logger.Logger, keys KeyProvider) *DecryptActivity {
+	return &DecryptActivity{
+		BaseActivity: activities.BaseActivity{
+			ActivityName: activityName,
+			Logger:       logger,
+		},
+		keys: keys,
+	}
+}
+
+// Arguments declares the arguments of the JWE decrypt activity
+func (a *DecryptActivity) Arguments() any {
+	return &DecryptArgs{}
+}
+
+func (a *DecryptActivity) Execute(ctx context.Context, arguments map[string]any) (interface{}, error) {
+	var args DecryptArgs
+	if err := utils.ParseAndValidateArgs(ctx, arguments, &args); err != nil {
+		a.GetLogger().ErrorContextf(ctx, "Invalid JWE decrypt arguments: %v", err)
+		return nil, activities.NewActivityError(
+			activities.ErrInvalidArguments,
+			"Invalid JWE decrypt arguments",
+			"JWEDecrypt",
+		).WithCause(err)
+	}
+	if args.KeyManagementAlgorithm == "" {
+		args.KeyManagementAlgorithm = DefaultKeyManagementAlgorithm
+	}
+	if args.ContentEncryptionAlgorithm == "" {
+		args.ContentEncryptionAlgorithm = DefaultContentEncryptionAlgorithm
+	}
+
+	// Only the expected algorithms are accepted, so that a token cannot choose weaker ones
+	object, err := gojose.ParseEncrypted(
+		args.Token,
+		[]gojose.KeyAlgorithm{gojose.KeyAlgorithm(args.KeyManagementAlgorithm)},
+		[]gojose.ContentEncryption{gojose.ContentEncryption(args.ContentEncryptionAlgorithm)},
+	)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWEDecryptError,
+			"Failed to parse JWE",
+			"JWEDecrypt",
+		).WithArguments(map[string]interface{}{
+			"keyManagementAlgorithm":     args.KeyManagementAlgorithm,
+			"contentEncryptionAlgorithm": args.ContentEncryptionAlgorithm,
+		}).WithCause(err)
+	}
+
+	keyID := args.KeyID
+	if keyID == "" {
+		keyID = object.Header.KeyID
+	}
+	key, err := resolveKey(ctx, a.keys, "privateKey", args.PrivateKey, keyID)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrKeyError,
+			"Failed to resolve the JWE decryption key",
+			"JWEDecrypt",
+		).WithArguments(map[string]interface{}{
+			"keyId": keyID,
+		}).WithCause(err)
+	}
+
+	a.GetLogger().DebugContextf(ctx, "Decrypting JWE with %s and %s", args.KeyManagementAlgorithm, args.ContentEncryptionAlgorithm)
+
+	payload, err := object.Decrypt(key)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWEDecryptError,
+			"Failed to decrypt JWE",
+			"JWEDecrypt",
+		).WithCause(err)
+	}
+
+	return decodePayload(payload), nil
+}
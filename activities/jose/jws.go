@@ -0,0 +1,201 @@
+package jose
+
+import (
+	"context"
+
+	gojose "github.com/go-jose/go-jose/v4"
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+)
+
+// DefaultSignatureAlgorithm is the JWS signature algorithm used when none is given
+const DefaultSignatureAlgorithm = string(gojose.RS256)
+
+// SignArgs represents the arguments for the JWS sign activity
+type SignArgs struct {
+	Payload    any    `arg:"payload" required:"true"` // strings are signed as they are, other values as JSON
+	PrivateKey any    `arg:"privateKey"`              // JWK or PEM, used instead of the key provider
+	KeyID      string `arg:"keyId"`
+	Algorithm  string `arg:"algorithm" default:"RS256" validate:"oneof=RS256 RS384 RS512 PS256 PS384 PS512 ES256 ES384 ES512 EdDSA HS256 HS384 HS512"`
+}
+
+// VerifyArgs represents the arguments for the JWS verify activity
+type VerifyArgs struct {
+	Token     string `arg:"token" required:"true"`
+	PublicKey any    `arg:"publicKey"` // JWK or PEM, used instead of the key provider
+	KeyID     string `arg:"keyId"`     // defaults to the kid header of the token
+	Algorithm string `arg:"algorithm" default:"RS256" validate:"oneof=RS256 RS384 RS512 PS256 PS384 PS512 ES256 ES384 ES512 EdDSA HS256 HS384 HS512"`
+}
+
+// SignActivity signs a payload into a JWS in compact serialization
+type SignActivity struct {
+	activities.BaseActivity
+	keys KeyProvider
+}
+
+// NewJWSSign creates a new JWS sign activity. The key provider supplies the keys referenced with the keyId
+// argument, and can be nil.
+func NewJWSSign(activityName string, logger logger.Logger, keys KeyProvider) *SignActivity {
+	return &SignActivity{
+		BaseActivity: activities.BaseActivity{
+			ActivityName: activityName,
+			Logger:       logger,
+		},
+		keys: keys,
+	}
+}
+
+// Arguments declares the arguments of the JWS sign activity
+func (a *SignActivity) Arguments() any {
+	return &SignArgs{}
+}
+
+func (a *SignActivity) Execute(ctx context.Context, arguments map[string]any) (interface{}, error) {
+	var args SignArgs
+	if err := utils.ParseAndValidateArgs(ctx, arguments, &args); err != nil {
+		a.GetLogger().ErrorContextf(ctx, "Invalid JWS sign arguments: %v", err)
+		return nil, activities.NewActivityError(
+			activities.ErrInvalidArguments,
+			"Invalid JWS sign arguments",
+			"JWSSign",
+		).WithCause(err)
+	}
+	if args.Algorithm == "" {
+		args.Algorithm = DefaultSignatureAlgorithm
+	}
+
+	key, err := resolveKey(ctx, a.keys, "privateKey", args.PrivateKey, args.KeyID)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrKeyError,
+			"Failed to resolve the JWS signing key",
+			"JWSSign",
+		).WithArguments(map[string]interface{}{
+			"keyId": args.KeyID,
+		}).WithCause(err)
+	}
+
+	payload, err := encodePayload(args.Payload)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWSSignError,
+			"Failed to marshal JWS payload",
+			"JWSSign",
+		).WithCause(err)
+	}
+
+	a.GetLogger().DebugContextf(ctx, "Signing JWS with %s", args.Algorithm)
+
+	options := &gojose.SignerOptions{}
+	if args.KeyID != "" {
+		options.WithHeader("kid", args.KeyID)
+	}
+	signer, err := gojose.NewSigner(gojose.SigningKey{Algorithm: gojose.SignatureAlgorithm(args.Algorithm), Key: key}, options)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWSSignError,
+			"Failed to create JWS signer",
+			"JWSSign",
+		).WithArguments(map[string]interface{}{
+			"algorithm": args.Algorithm,
+		}).WithCause(err)
+	}
+
+	object, err := signer.Sign(payload)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWSSignError,
+			"Failed to sign JWS payload",
+			"JWSSign",
+		).WithCause(err)
+	}
+	token, err := object.CompactSerialize()
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWSSignError,
+			"Failed to serialize JWS",
+			"JWSSign",
+		).WithCause(err)
+	}
+
+	return token, nil
+}
+
+// VerifyActivity verifies a JWS, returning its payload decoded from JSON, or as a string if it is not JSON
+type VerifyActivity struct {
+	activities.BaseActivity
+	keys KeyProvider
+}
+
+// NewJWSVerify creates a new JWS verify activity. The key provider supplies the keys referenced with the keyId
+// argument or the kid header of the token, and can be nil.
+func NewJWSVerify(activityName string, logger logger.Logger, keys KeyProvider) *VerifyActivity {
+	return &VerifyActivity{
+		BaseActivity: activities.BaseActivity{
+			ActivityName: activityName,
+			Logger:       logger,
+		},
+		keys: keys,
+	}
+}
+
+// Arguments declares the arguments of the JWS verify activity
+func (a *VerifyActivity) Arguments() any {
+	return &VerifyArgs{}
+}
+
+func (a *VerifyActivity) Execute(ctx context.Context, arguments map[string]any) (interface{}, error) {
+	var args VerifyArgs
+	if err := utils.ParseAndValidateArgs(ctx, arguments, &args); err != nil {
+		a.GetLogger().ErrorContextf(ctx, "Invalid JWS verify arguments: %v", err)
+		return nil, activities.NewActivityError(
+			activities.ErrInvalidArguments,
+			"Invalid JWS verify arguments",
+			"JWSVerify",
+		).WithCause(err)
+	}
+	if args.Algorithm == "" {
+		args.Algorithm = DefaultSignatureAlgorithm
+	}
+
+	// Only the expected algorithm is accepted, so that a token cannot choose another one
+	object, err := gojose.ParseSigned(args.Token, []gojose.SignatureAlgorithm{gojose.SignatureAlgorithm(args.Algorithm)})
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWSVerifyError,
+			"Failed to parse JWS",
+			"JWSVerify",
+		).WithArguments(map[string]interface{}{
+			"algorithm": args.Algorithm,
+		}).WithCause(err)
+	}
+
+	keyID := args.KeyID
+	if keyID == "" && len(object.Signatures) > 0 {
+		keyID = object.Signatures[0].Header.KeyID
+	}
+	key, err := resolveKey(ctx, a.keys, "publicKey", args.PublicKey, keyID)
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrKeyError,
+			"Failed to resolve the JWS verification key",
+			"JWSVerify",
+		).WithArguments(map[string]interface{}{
+			"keyId": keyID,
+		}).WithCause(err)
+	}
+
+	a.GetLogger().DebugContextf(ctx, "Verifying JWS with %s", args.Algorithm)
+
+	payload, err := object.Verify(publicKey(key))
+	if err != nil {
+		return nil, activities.NewActivityError(
+			activities.ErrJWSVerifyError,
+			"Failed to verify JWS signature",
+			"JWSVerify",
+		).WithCause(err)
+	}
+
+	return decodePayload(payload), nil
+}
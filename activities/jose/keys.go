@@ -0,0 +1,158 @@
+package jose
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	gojose "github.com/go-jose/go-jose/v4"
+)
+
+// KeyProvider supplies the keys that the JWE and JWS activities reference by id, e.g. from a key management
+// service or a JWKS endpoint
+type KeyProvider interface {
+	// Key returns the key with the id, in any of the forms ParseKey accepts
+	Key(ctx context.Context, keyID string) (any, error)
+}
+
+// StaticKeys is a KeyProvider of keys held in memory, by key id
+type StaticKeys map[string]any
+
+// Key returns the key with the id
+func (k StaticKeys) Key(ctx context.Context, keyID string) (any, error) {
+	key, ok := k[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key '%s' not found", keyID)
+	}
+	return key, nil
+}
+
+// ParseKey parses a key given as a JWK, either JSON or a decoded JSON object, or as PEM: a PKIX or PKCS #1 public
+// key, a PKCS #1, PKCS #8 or SEC 1 private key, or a certificate. Crypto keys, e.g. *rsa.PrivateKey, and []byte
+// symmetric keys are returned as they are.
+func ParseKey(key any) (any, error) {
+	switch key := key.(type) {
+	case nil:
+		return nil, errors.New("key is empty")
+	case string:
+		trimmed := strings.TrimSpace(key)
+		switch {
+		case strings.HasPrefix(trimmed, "{"):
+			return parseJWK([]byte(trimmed))
+		case strings.HasPrefix(trimmed, "-----BEGIN"):
+			return parsePEM([]byte(trimmed))
+		}
+		return nil, errors.New("key is neither a JWK nor PEM encoded")
+	case map[string]any:
+		raw, err := json.Marshal(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWK: %w", err)
+		}
+		return parseJWK(raw)
+	case gojose.JSONWebKey:
+		return &key, nil
+	}
+	return key, nil
+}
+
+// parseJWK parses a JSON Web Key. The JWK is kept rather than its key, so that its key id is used in headers.
+func parseJWK(raw []byte) (any, error) {
+	jwk := &gojose.JSONWebKey{}
+	if err := jwk.UnmarshalJSON(raw); err != nil {
+		return nil, fmt.Errorf("invalid JWK: %w", err)
+	}
+	return jwk, nil
+}
+
+// parsePEM parses the first PEM block of the data
+func parsePEM(data []byte) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM: no PEM block found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("invalid PEM: unsupported block type '%s'", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid PEM %s: %w", strings.ToLower(block.Type), err)
+	}
+	return key, nil
+}
+
+// publicKey returns the public half of an asymmetric private key, so that a key pair can be given wherever a
+// public key is expected. Public and symmetric keys are returned as they are.
+func publicKey(key any) any {
+	switch key := key.(type) {
+	case *gojose.JSONWebKey:
+		if _, symmetric := key.Key.([]byte); symmetric || key.IsPublic() {
+			return key
+		}
+		public := key.Public()
+		return &public
+	case crypto.Signer:
+		return key.Public()
+	case crypto.Decrypter:
+		return key.Public()
+	}
+	return key
+}
+
+// resolveKey returns the key given by the argument, or else the key the provider has under the key id
+func resolveKey(ctx context.Context, keys KeyProvider, argument string, key any, keyID string) (any, error) {
+	if key != nil {
+		return ParseKey(key)
+	}
+	if keyID == "" {
+		return nil, fmt.Errorf("no key given: set either '%s' or 'keyId'", argument)
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("key '%s' cannot be looked up without a key provider", keyID)
+	}
+	provided, err := keys.Key(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key '%s': %w", keyID, err)
+	}
+	return ParseKey(provided)
+}
+
+// encodePayload returns the bytes to encrypt or sign: strings as they are, other values as JSON
+func encodePayload(payload any) ([]byte, error) {
+	if str, ok := payload.(string); ok {
+		return []byte(str), nil
+	}
+	return json.Marshal(payload)
+}
+
+// decodePayload returns a decrypted or verified payload decoded from JSON, or as a string if it is not JSON
+func decodePayload(payload []byte) any {
+	var value any
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return string(payload)
+	}
+	return value
+}
@@ -27,6 +27,11 @@ func New(activityName string, logger logger.Logger) *TransformActivity {
 	}
 }
 
+// Arguments declares the arguments of the JQ transform activity
+func (a *TransformActivity) Arguments() any {
+	return &TransformArgs{}
+}
+
 func (a *TransformActivity) Execute(ctx context.Context, arguments map[string]any) (interface{}, error) {
 	var args TransformArgs
 	if err := utils.ParseAndValidateArgs(ctx, arguments, &args); err != nil {
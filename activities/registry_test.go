@@ -246,6 +246,28 @@ func TestActivityRegistry(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "value", result)
 	})
+
+	t.Run("Operation Lookup", func(t *testing.T) {
+		registry := NewRegistry(logger)
+		err := registry.RegisterActivity("SendSMS", &MockActivity{BaseActivity: &BaseActivity{}})
+		require.NoError(t, err)
+
+		err = registry.RegisterOperation("sms:send", "SendSMS")
+		require.NoError(t, err)
+		err = registry.RegisterOperation("sms:send", "SendEmail")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "already mapped")
+
+		// Operations resolve through their mapping or to the activity of the same name
+		name, ok := registry.LookupOperation("sms:send")
+		require.True(t, ok)
+		require.Equal(t, "SendSMS", name)
+		name, ok = registry.LookupOperation("SendSMS")
+		require.True(t, ok)
+		require.Equal(t, "SendSMS", name)
+		_, ok = registry.LookupOperation("email:send")
+		require.False(t, ok)
+	})
 }
 
 func TestRegisterActivityStruct(t *testing.T) {
@@ -3,10 +3,13 @@ package config
 import (
 	"github.com/kshitiz1403/jsonjuggler/activities"
 	"github.com/kshitiz1403/jsonjuggler/activities/http"
+	"github.com/kshitiz1403/jsonjuggler/activities/jose"
 	"github.com/kshitiz1403/jsonjuggler/activities/jq"
 	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/events"
 	"github.com/kshitiz1403/jsonjuggler/logger"
 	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/persistence"
 	"github.com/kshitiz1403/jsonjuggler/telemetry"
 )
 
@@ -18,10 +21,22 @@ type Config struct {
 	CustomActivities map[string]activities.Activity
 	// ActivityStructs is a slice of activity structs to register
 	ActivityStructs []interface{}
+	// Operations maps the operations of custom workflow functions to activity names
+	Operations map[string]string
 	// Logger is the logger implementation to use
 	Logger logger.Logger
 	// Telemetry configuration
 	TelemetryConfig *telemetry.Config
+	// Store is the persistence store workflow runs are checkpointed to
+	Store persistence.Store
+	// EventBus is the bus event states and event-based switch states consume events from
+	EventBus events.Bus
+	// SubstringErrorMatching also matches onErrors references contained in the error message
+	SubstringErrorMatching bool
+	// WorkflowDirs are the directories workflow definitions are loaded from into the workflow registry
+	WorkflowDirs []string
+	// KeyProvider supplies the keys the JWE and JWS activities reference by id
+	KeyProvider jose.KeyProvider
 }
 
 // Option is a function that modifies Config
@@ -34,6 +49,13 @@ func WithActivity(name string, activity activities.Activity) Option {
 	}
 }
 
+// WithOperation maps the operation of custom workflow functions, e.g. "sms:send", to the activity that runs it
+func WithOperation(operation string, activityName string) Option {
+	return func(c *Config) {
+		c.Operations[operation] = activityName
+	}
+}
+
 // WithDebug adds debug enabled to the configuration
 func WithDebug(enabled bool) Option {
 	return func(c *Config) {
@@ -62,10 +84,49 @@ func WithTelemetry(cfg *telemetry.Config) Option {
 	}
 }
 
+// WithPersistence checkpoints every workflow run to the store, so that runs can be resumed with Engine.Resume
+func WithPersistence(store persistence.Store) Option {
+	return func(c *Config) {
+		c.Store = store
+	}
+}
+
+// WithEventBus sets the bus workflows consume events from, e.g. channel.New() for in-process events
+func WithEventBus(bus events.Bus) Option {
+	return func(c *Config) {
+		c.EventBus = bus
+	}
+}
+
+// WithSubstringErrorMatching makes onErrors references fall back to matching errors whose message contains
+// the reference, as an alternative to declaring the errors in the workflow
+func WithSubstringErrorMatching() Option {
+	return func(c *Config) {
+		c.SubstringErrorMatching = true
+	}
+}
+
+// WithWorkflowDir loads the workflow definitions in the directory, so that they can be executed with
+// Engine.ExecuteByID and invoked as sub-flows
+func WithWorkflowDir(dir string) Option {
+	return func(c *Config) {
+		c.WorkflowDirs = append(c.WorkflowDirs, dir)
+	}
+}
+
+// WithKeyProvider sets the provider of the keys the JWE and JWS activities reference with their keyId argument or
+// the kid header of a token, e.g. jose.StaticKeys
+func WithKeyProvider(provider jose.KeyProvider) Option {
+	return func(c *Config) {
+		c.KeyProvider = provider
+	}
+}
+
 // Initialize creates a new JSONJuggler engine with the given configuration options
 func Initialize(opts ...Option) (*engine.Engine, error) {
 	config := &Config{
 		CustomActivities: make(map[string]activities.Activity),
+		Operations:       make(map[string]string),
 		Logger:           zap.NewLogger(logger.InfoLevel), // Default logger
 	}
 
@@ -88,7 +149,7 @@ func Initialize(opts ...Option) (*engine.Engine, error) {
 	registry := activities.NewRegistry(config.Logger)
 
 	// Register default activities
-	registerBuiltInActivities(registry)
+	registerBuiltInActivities(registry, config.KeyProvider)
 
 	// Register custom activities
 	for name, activity := range config.CustomActivities {
@@ -104,12 +165,51 @@ func Initialize(opts ...Option) (*engine.Engine, error) {
 		}
 	}
 
-	return engine.NewEngine(registry, config.DebugEnabled, config.Logger, tel), nil
+	// Map custom function operations to activities
+	for operation, name := range config.Operations {
+		if err := registry.RegisterOperation(operation, name); err != nil {
+			return nil, err
+		}
+	}
+
+	var engineOpts []engine.Option
+	if config.Store != nil {
+		engineOpts = append(engineOpts, engine.WithStore(config.Store))
+	}
+	if config.EventBus != nil {
+		engineOpts = append(engineOpts, engine.WithEventBus(config.EventBus))
+	}
+	if config.SubstringErrorMatching {
+		engineOpts = append(engineOpts, engine.WithSubstringErrorMatching())
+	}
+
+	e := engine.NewEngine(registry, config.DebugEnabled, config.Logger, tel, engineOpts...)
+
+	// Load workflows once all activities are registered, so that they can be validated against them
+	for _, dir := range config.WorkflowDirs {
+		if err := e.LoadWorkflows(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
 }
 
-func registerBuiltInActivities(registry *activities.Registry) {
+func registerBuiltInActivities(registry *activities.Registry, keys jose.KeyProvider) {
 	// Here we'll register all the built-in activities
 	// For example:
 	registry.RegisterActivity("JQ", jq.New("JQ", registry.GetLogger()))
 	registry.RegisterActivity("HTTPRequest", http.New("HTTPRequest", registry.GetLogger()))
+	registry.RegisterActivity("JWEEncrypt", jose.NewJWEEncrypt("JWEEncrypt", registry.GetLogger(), keys))
+	registry.RegisterActivity("JWEDecrypt", jose.NewJWEDecrypt("JWEDecrypt", registry.GetLogger(), keys))
+	registry.RegisterActivity("JWSSign", jose.NewJWSSign("JWSSign", registry.GetLogger(), keys))
+	registry.RegisterActivity("JWSVerify", jose.NewJWSVerify("JWSVerify", registry.GetLogger(), keys))
+
+	// Operations of the built-in activities for custom workflow functions
+	registry.RegisterOperation("jq:transform", "JQ")
+	registry.RegisterOperation("http:request", "HTTPRequest")
+	registry.RegisterOperation("jwe:encrypt", "JWEEncrypt")
+	registry.RegisterOperation("jwe:decrypt", "JWEDecrypt")
+	registry.RegisterOperation("jws:sign", "JWSSign")
+	registry.RegisterOperation("jws:verify", "JWSVerify")
 }
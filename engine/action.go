@@ -17,6 +17,10 @@ import (
 
 // executeAction executes a single action and returns its result
 func (e *Engine) executeAction(ctx context.Context, action sw.Action, data *WorkflowData, stateExec *StateExecution) (result interface{}, err error) {
+	if action.SubFlowRef != nil {
+		return e.executeSubFlow(ctx, action, data, stateExec)
+	}
+
 	ctx = context.WithValue(ctx, logger.ActivityNameKey, action.FunctionRef.RefName)
 
 	startTime := time.Now()
@@ -69,17 +73,36 @@ func (e *Engine) executeAction(ctx context.Context, action sw.Action, data *Work
 		}
 	}()
 
-	// Phase 1: Activity lookup with its own span
-	activity, err := e.lookupActivityWithTelemetry(ctx, action.FunctionRef.RefName)
+	// Phase 1: Function resolution and activity lookup with its own span
+	function, err := e.resolveFunction(ctx, action.FunctionRef.RefName)
 	if err != nil {
 		if actionResult != nil {
 			actionResult.Error = err.Error()
 		}
 		return nil, err
 	}
+	// Expression functions are evaluated by the engine and have no activity
+	var activity activities.Activity
+	if function.Activity != "" {
+		activity, err = e.lookupActivityWithTelemetry(ctx, function.Activity)
+		if err != nil {
+			if actionResult != nil {
+				actionResult.Error = err.Error()
+			}
+			return nil, err
+		}
+	}
 
 	// Phase 2: Argument resolution with its own span
 	arguments, err := e.resolveArgumentsWithTelemetry(ctx, action.FunctionRef.RefName, action.FunctionRef.Arguments, data)
+	if err == nil {
+		arguments, err = functionArguments(function, arguments)
+		if err != nil {
+			err = NewWorkflowError(ErrActivityArgInvalid, "Invalid function arguments").
+				WithActivity(action.FunctionRef.RefName).
+				WithCause(err)
+		}
+	}
 	if err != nil {
 		if actionResult != nil {
 			actionResult.Error = err.Error()
@@ -91,8 +114,20 @@ func (e *Engine) executeAction(ctx context.Context, action sw.Action, data *Work
 		actionResult.Arguments = arguments
 	}
 
-	// Phase 3: Activity execution with its own span
-	result, err = e.executeActivityWithTelemetry(ctx, action.FunctionRef.RefName, activity, arguments)
+	// Phase 3: Activity execution with its own span, retried according to the action's retry policy
+	// and bounded by the state's actionExecTimeout
+	if activity == nil {
+		result, err = e.evaluateExpressionFunction(ctx, function, arguments, data)
+	} else {
+		actionCtx, cancel := withTimeout(ctx, actionExecTimeout, actionTimeoutFromContext(ctx))
+		defer cancel()
+		result, err = e.executeActivityWithRetry(actionCtx, action, activity, arguments, actionResult)
+		if err != nil {
+			if timeout := expiredTimeout(actionCtx); timeout != nil && ctx.Err() == nil {
+				err = timeout.newError(err).WithActivity(action.FunctionRef.RefName)
+			}
+		}
+	}
 	if err != nil {
 		if actionResult != nil {
 			actionResult.Error = err.Error()
@@ -117,14 +152,110 @@ func (e *Engine) executeAction(ctx context.Context, action sw.Action, data *Work
 	return result, nil
 }
 
+// performAction performs an action of a state. The action is skipped when its condition does not evaluate to
+// true against the state data; otherwise it runs against its input data, after its sleep before duration and
+// followed by its sleep after duration.
+func (e *Engine) performAction(ctx context.Context, action sw.Action, data *WorkflowData, stateExec *StateExecution) (result interface{}, skipped bool, err error) {
+	if action.Condition != "" {
+		matched, err := e.evaluateFunctionExpression(ctx, action.Condition, data)
+		if err != nil {
+			e.logger.ErrorContextf(ctx, "Failed to evaluate condition of action '%s': %v", actionName(action), err)
+			return nil, false, NewWorkflowError(ErrExpressionEval, "Failed to evaluate action condition").
+				WithActivity(actionName(action)).
+				WithExpression(action.Condition).
+				WithCause(err)
+		}
+		if matched != true {
+			e.logger.InfoContextf(ctx, "Skipping action '%s': condition '%s' is not true", actionName(action), action.Condition)
+			e.recordSkippedAction(action, stateExec)
+			return nil, true, nil
+		}
+	}
+
+	if err := e.sleepAction(ctx, action, action.Sleep.Before); err != nil {
+		return nil, false, err
+	}
+
+	actionData, err := e.actionInputData(ctx, action, data)
+	if err != nil {
+		return nil, false, err
+	}
+	result, err = e.executeAction(ctx, action, actionData, stateExec)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := e.sleepAction(ctx, action, action.Sleep.After); err != nil {
+		return nil, false, err
+	}
+	return result, false, nil
+}
+
+// sleepAction waits for one of the action's sleep durations, unless the context is done first
+func (e *Engine) sleepAction(ctx context.Context, action sw.Action, duration string) error {
+	if duration == "" {
+		return nil
+	}
+	d, err := utils.ParseISODuration(duration)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Invalid sleep duration of action '%s': %v", actionName(action), err)
+		return NewWorkflowError(ErrExpressionInvalid, "Invalid action sleep duration").
+			WithActivity(actionName(action)).
+			WithContext("duration", duration).
+			WithCause(err)
+	}
+
+	e.logger.DebugContextf(ctx, "Action '%s' sleeping for %v", actionName(action), d)
+	timer := time.NewTimer(d)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	case <-timer.C:
+	}
+	return nil
+}
+
+// recordSkippedAction records an action whose condition was not true in the debug data
+func (e *Engine) recordSkippedAction(action sw.Action, stateExec *StateExecution) {
+	if !e.debugEnabled || stateExec == nil {
+		return
+	}
+	now := time.Now()
+	actionResult := ActionResult{StartTime: now, EndTime: now, Skipped: true}
+	if action.SubFlowRef != nil {
+		actionResult.WorkflowID = action.SubFlowRef.WorkflowID
+	} else {
+		actionResult.ActivityName = actionName(action)
+	}
+	stateExec.Actions = append(stateExec.Actions, actionResult)
+}
+
+// actionName returns the name an action is reported under: the function it calls or the workflow it invokes
+func actionName(action sw.Action) string {
+	switch {
+	case action.FunctionRef != nil:
+		return action.FunctionRef.RefName
+	case action.SubFlowRef != nil:
+		return action.SubFlowRef.WorkflowID
+	}
+	return action.Name
+}
+
 // evaluateArguments evaluates all arguments in a map recursively
 func (e *Engine) evaluateArguments(ctx context.Context, args map[string]sw.Object, data *WorkflowData) (map[string]any, error) {
 	arguments := convertToAnyMap(args)
 	return utils.EvaluateArgumentMap(arguments, data.ToMap())
 }
 
-// executeActions executes a list of actions
-func (e *Engine) executeActions(ctx context.Context, actions []sw.Action, data *WorkflowData, stateExec *StateExecution) (result *StateResult, err error) {
+// executeActions executes a list of actions one after another
+func (e *Engine) executeActions(ctx context.Context, actions []sw.Action, data *WorkflowData, stateExec *StateExecution) (*StateResult, error) {
+	return e.executeActionsInMode(ctx, sw.ActionModeSequential, actions, data, stateExec)
+}
+
+// executeActionsInMode executes a list of actions. Sequential actions run one after another, each seeing the state
+// data as updated by the previous ones; parallel actions run concurrently.
+func (e *Engine) executeActionsInMode(ctx context.Context, mode sw.ActionMode, actions []sw.Action, data *WorkflowData, stateExec *StateExecution) (result *StateResult, err error) {
 	currentResult := data.Current
 
 	// Add action group span for operation states
@@ -147,9 +278,14 @@ func (e *Engine) executeActions(ctx context.Context, actions []sw.Action, data *
 		}()
 	}
 
+	if mode == sw.ActionModeParallel && len(actions) > 1 {
+		return e.executeParallelActions(ctx, actions, data, stateExec)
+	}
+
 	for _, action := range actions {
 		var actionResult interface{}
-		actionResult, err = e.executeAction(ctx, action, data, stateExec)
+		var skipped bool
+		actionResult, skipped, err = e.performAction(ctx, action, data, stateExec)
 		if err != nil {
 			result = &StateResult{
 				Data:  currentResult,
@@ -157,9 +293,16 @@ func (e *Engine) executeActions(ctx context.Context, actions []sw.Action, data *
 			}
 			return result, err
 		}
-		// Update current data
-		data.Current = actionResult
-		currentResult = actionResult
+		if skipped {
+			continue
+		}
+
+		// Update current data according to the action's data filter
+		currentResult, err = e.applyActionResults(ctx, action, currentResult, actionResult)
+		if err != nil {
+			return &StateResult{Data: data.Current, Error: err}, err
+		}
+		data.Current = currentResult
 	}
 
 	result = &StateResult{
@@ -168,6 +311,82 @@ func (e *Engine) executeActions(ctx context.Context, actions []sw.Action, data *
 	return result, nil
 }
 
+// actionOutcome carries the result of a single parallel action back to the state
+type actionOutcome struct {
+	index   int
+	result  interface{}
+	skipped bool
+	err     error
+}
+
+// executeParallelActions runs the actions concurrently, each against its own copy of the state data. Once all
+// actions completed, the results of the actions that were not skipped are applied to the state data in action
+// definition order. When an action fails the remaining actions are cancelled and the state fails with the first
+// error.
+func (e *Engine) executeParallelActions(ctx context.Context, actions []sw.Action, data *WorkflowData, stateExec *StateExecution) (*StateResult, error) {
+	e.logger.DebugContextf(ctx, "Running %d actions in parallel", len(actions))
+
+	actionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Every action records its debug data separately, so that the state's actions are listed in definition order
+	actionExecs := make([]*StateExecution, len(actions))
+	outcomes := make(chan actionOutcome, len(actions))
+	for i, action := range actions {
+		if stateExec != nil {
+			actionExecs[i] = &StateExecution{}
+		}
+
+		go func(index int, action sw.Action, actionData *WorkflowData, actionExec *StateExecution) {
+			result, skipped, err := e.performAction(actionCtx, action, actionData, actionExec)
+			outcomes <- actionOutcome{index: index, result: result, skipped: skipped, err: err}
+		}(i, action, data.clone(), actionExecs[i])
+	}
+
+	// Wait for every action to return so that no action outlives the state
+	results := make([]interface{}, len(actions))
+	skipped := make([]bool, len(actions))
+	var actionErr error
+	for range actions {
+		outcome := <-outcomes
+		if outcome.err != nil {
+			if actionErr == nil {
+				actionErr = outcome.err
+				e.logger.ErrorContextf(ctx, "Action '%s' failed, cancelling remaining actions: %v", actionName(actions[outcome.index]), outcome.err)
+				cancel()
+			}
+			continue
+		}
+		results[outcome.index] = outcome.result
+		skipped[outcome.index] = outcome.skipped
+	}
+
+	if stateExec != nil {
+		for _, actionExec := range actionExecs {
+			stateExec.Actions = append(stateExec.Actions, actionExec.Actions...)
+		}
+	}
+
+	if actionErr != nil {
+		return &StateResult{Data: data.Current, Error: actionErr}, actionErr
+	}
+
+	currentResult := data.Current
+	for i, action := range actions {
+		if skipped[i] {
+			continue
+		}
+		var err error
+		currentResult, err = e.applyActionResults(ctx, action, currentResult, results[i])
+		if err != nil {
+			return &StateResult{Data: data.Current, Error: err}, err
+		}
+	}
+	data.Current = currentResult
+
+	return &StateResult{Data: currentResult}, nil
+}
+
 // convertToAnyMap converts model.Object map to map[string]any
 func convertToAnyMap(m map[string]sw.Object) map[string]any {
 	// TODO: This is a temporary solution to convert the map to a map[string]any
@@ -281,7 +500,7 @@ func (e *Engine) resolveArgumentsWithTelemetry(ctx context.Context, activityName
 			WithActivity(activityName).
 			WithCause(err)
 		e.logger.ErrorContextf(ctx, "Failed to evaluate arguments: %v", err)
-		return nil, err // Not an activity error. TODO: @Pranithkampelly what are your thoughts on this? Should we enable onErrors for all type of errors or only ActivityErrors? Moreover, getting the arguments is not an activity error, so this would not be handled by onErrors. False negative.
+		return nil, err
 	}
 
 	return arguments, nil
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/events"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// callbacks tracks the runs that are parked in a callback state, waiting for Engine.Callback
+type callbacks struct {
+	mu      sync.Mutex
+	waiting map[string]*callbackWaiter
+}
+
+// callbackWaiter is a run waiting for its callback event
+type callbackWaiter struct {
+	stateName string
+	payload   chan interface{}
+}
+
+func newCallbacks() *callbacks {
+	return &callbacks{waiting: make(map[string]*callbackWaiter)}
+}
+
+// register parks the run until its callback is delivered. The waiter is registered before the state's
+// action runs, so a callback that arrives while the action is still completing is not lost.
+func (c *callbacks) register(runID, stateName string) *callbackWaiter {
+	waiter := &callbackWaiter{
+		stateName: stateName,
+		payload:   make(chan interface{}, 1),
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waiting[runID] = waiter
+	return waiter
+}
+
+// unregister removes the run's waiter, if it is still the given one
+func (c *callbacks) unregister(runID string, waiter *callbackWaiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.waiting[runID] == waiter {
+		delete(c.waiting, runID)
+	}
+}
+
+// deliver hands the payload to the waiting run. Only the first callback for a state is accepted.
+func (c *callbacks) deliver(runID string, payload interface{}) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiter, ok := c.waiting[runID]
+	if !ok {
+		return "", false
+	}
+	delete(c.waiting, runID)
+	waiter.payload <- payload
+	return waiter.stateName, true
+}
+
+// RunIDFromContext returns the ID of the workflow run the context belongs to. Activities use it to tell
+// external systems which run to call back with Engine.Callback.
+func RunIDFromContext(ctx context.Context) string {
+	if exec := executionFromContext(ctx); exec != nil {
+		return exec.runID
+	}
+	return ""
+}
+
+// Callback delivers the payload of a callback event to a run that is waiting in a callback state.
+// The payload is merged into the state data using the state's eventDataFilter and the run continues.
+// Only runs executing in this engine can receive callbacks: a run that was interrupted while waiting
+// must be resumed with Resume first, which waits for the callback again without repeating the action.
+func (e *Engine) Callback(ctx context.Context, runID string, payload interface{}) error {
+	stateName, ok := e.callbacks.deliver(runID, deepCopy(payload))
+	if !ok {
+		e.logger.ErrorContextf(ctx, "Run %s is not waiting for a callback", runID)
+		return NewWorkflowError(ErrRunNotFound, fmt.Sprintf("run '%s' is not waiting for a callback", runID)).
+			WithContext("runId", runID)
+	}
+
+	e.logger.InfoContextf(ctx, "Delivered callback to run %s in state '%s'", runID, stateName)
+	return nil
+}
+
+// executeCallbackState performs the state's action and then waits, at most for the eventTimeout, for the
+// callback event to be delivered with Engine.Callback. An expired eventTimeout is routed through onErrors.
+func (e *Engine) executeCallbackState(ctx context.Context, state *sw.CallbackState, data *WorkflowData, stateExec *StateExecution) (*StateResult, error) {
+	definition, ok := findEventDefinition(workflowFromContext(ctx), state.EventRef)
+	if !ok {
+		return nil, NewWorkflowError(ErrEventNotFound, fmt.Sprintf("event '%s' is not defined", state.EventRef)).
+			WithState(state.GetName()).
+			WithContext("eventRef", state.EventRef)
+	}
+
+	timeout, err := eventTimeout(workflowFromContext(ctx), state)
+	if err != nil {
+		return nil, err
+	}
+
+	runID := RunIDFromContext(ctx)
+	waiter := e.callbacks.register(runID, state.GetName())
+	defer e.callbacks.unregister(runID, waiter)
+
+	if exec := executionFromContext(ctx); exec.resumesCallback(state.GetName()) {
+		// The action was performed before the run was interrupted
+		e.logger.InfoContextf(ctx, "Resuming wait for callback event '%s'", state.EventRef)
+		data.Current = deepCopy(exec.callback.Data)
+		exec.callback = nil
+	} else {
+		result, err := e.executeActions(ctx, []sw.Action{state.Action}, data, stateExec)
+		if err != nil {
+			return nil, err
+		}
+		data.Current = result.Data
+
+		if err := e.saveCallback(ctx, exec, state.GetName(), data.Current); err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := e.waitForCallback(ctx, state, definition, waiter, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	event := consumedEvent{
+		ref:        state.EventRef,
+		definition: definition,
+		event: events.Event{
+			Source: definition.Source,
+			Type:   definition.Type,
+			Time:   time.Now(),
+			Data:   payload,
+		},
+	}
+	if stateExec != nil {
+		stateExec.Events = append(stateExec.Events, ConsumedEvent{
+			EventRef: event.ref,
+			Source:   event.event.Source,
+			Type:     event.event.Type,
+			Time:     event.event.Time,
+			Data:     event.event.Data,
+		})
+	}
+
+	output, err := e.applyEventDataFilter(ctx, state.EventDataFilter, data.Current, event)
+	if err != nil {
+		return nil, err
+	}
+
+	var nextState string
+	if state.GetTransition() != nil {
+		nextState = state.GetTransition().NextState
+	}
+
+	return &StateResult{
+		Data:      output,
+		NextState: nextState,
+	}, nil
+}
+
+// waitForCallback waits for the payload delivered to the waiter
+func (e *Engine) waitForCallback(ctx context.Context, state *sw.CallbackState, definition sw.Event, waiter *callbackWaiter, timeout time.Duration) (payload interface{}, err error) {
+	if e.telemetry != nil {
+		var eventSpan trace.Span
+		ctx, eventSpan = e.telemetry.StartEventWaitSpan(ctx, state.GetName(), []string{state.EventRef})
+		defer func() {
+			if err != nil {
+				eventSpan.RecordError(err)
+				eventSpan.SetStatus(codes.Error, err.Error())
+			} else {
+				eventSpan.SetStatus(codes.Ok, "")
+			}
+			eventSpan.End()
+		}()
+	}
+
+	waitCtx, cancel := withTimeout(ctx, eventTimeoutName, timeout)
+	defer cancel()
+
+	e.logger.InfoContextf(ctx, "Waiting for callback event '%s'", state.EventRef)
+	select {
+	case payload = <-waiter.payload:
+		e.logger.InfoContextf(ctx, "Received callback event '%s'", state.EventRef)
+		if e.telemetry != nil {
+			e.telemetry.RecordEventConsumed(ctx, state.EventRef, definition.Type)
+		}
+		return payload, nil
+	case <-waitCtx.Done():
+		err = waitCtx.Err()
+		if timeout := expiredTimeout(waitCtx); timeout != nil {
+			err = timeout.newError(err).WithState(state.GetName())
+		}
+		e.logger.ErrorContextf(ctx, "Stopped waiting for callback: %v", err)
+		return nil, err
+	}
+}
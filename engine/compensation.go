@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// compensatedStateKey is the context key under which the name of the state being compensated is stored
+type compensatedStateKey struct{}
+
+// compensates reports whether taking the transition or end triggers compensation
+func compensates(transition *sw.Transition, end *sw.End) bool {
+	return (transition != nil && transition.Compensate) || (end != nil && end.Compensate)
+}
+
+// withCompensatedState returns a context marking the states executed with it as compensation of the named state
+func withCompensatedState(ctx context.Context, stateName string) context.Context {
+	return context.WithValue(ctx, compensatedStateKey{}, stateName)
+}
+
+// compensatedStateFromContext returns the state being compensated, or an empty name outside of compensation
+func compensatedStateFromContext(ctx context.Context) string {
+	stateName, _ := ctx.Value(compensatedStateKey{}).(string)
+	return stateName
+}
+
+// compensate undoes the completed states that define compensatedBy, in reverse order of completion.
+// Each compensation state receives the output of the state it compensates and may transition to further
+// compensation states. Compensation does not change the workflow's current data.
+func (e *Engine) compensate(ctx context.Context, exec *execution, data *WorkflowData) error {
+	for len(exec.compensable) > 0 {
+		last := len(exec.compensable) - 1
+		stateName := exec.compensable[last]
+		exec.compensable = exec.compensable[:last]
+
+		if err := e.compensateState(ctx, exec, data, exec.findState(stateName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compensateFailedRun undoes what a failed run completed so far. The run's error is reported either way, so a
+// compensation that fails is only logged. A cancelled run is not compensated, so that it can be resumed.
+func (e *Engine) compensateFailedRun(ctx context.Context, exec *execution, data *WorkflowData) {
+	if ctx.Err() != nil {
+		return
+	}
+	if err := e.compensate(ctx, exec, data); err != nil {
+		e.logger.ErrorContextf(ctx, "Compensation failed: %v", err)
+	}
+}
+
+// compensateState runs the compensation states of a single completed state
+func (e *Engine) compensateState(ctx context.Context, exec *execution, data *WorkflowData, state sw.State) (err error) {
+	if e.telemetry != nil {
+		var span trace.Span
+		ctx, span = e.telemetry.StartCompensationSpan(ctx, state.GetName(), state.GetCompensatedBy())
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			} else {
+				span.SetStatus(codes.Ok, "")
+			}
+			span.End()
+		}()
+	}
+
+	e.logger.InfoContextf(ctx, "Compensating state '%s' with '%s'", state.GetName(), state.GetCompensatedBy())
+	ctx = withCompensatedState(ctx, state.GetName())
+
+	compensationData := *data
+	compensationData.Current = deepCopy(data.States[state.GetName()])
+	compensationData.Error = nil
+
+	for next := state.GetCompensatedBy(); next != ""; {
+		compensation := exec.findState(next)
+		if compensation == nil {
+			return NewWorkflowError(ErrStateNotFound, fmt.Sprintf("compensation state '%s' not found", next)).
+				WithState(state.GetName())
+		}
+		if !compensation.GetUsedForCompensation() {
+			return NewWorkflowError(ErrStateInvalid, fmt.Sprintf("state '%s' is not marked usedForCompensation", next)).
+				WithState(state.GetName())
+		}
+
+		result, err := e.executeState(ctx, compensation, &compensationData)
+		if err != nil {
+			return NewWorkflowError(ErrCompensation, fmt.Sprintf("compensation of state '%s' failed", state.GetName())).
+				WithState(compensation.GetName()).
+				WithCause(err)
+		}
+
+		data.States[compensation.GetName()] = result.Data
+		compensationData.Current = result.Data
+		next = result.NextState
+	}
+	return nil
+}
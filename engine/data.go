@@ -10,6 +10,12 @@ type WorkflowData struct {
 	States map[string]interface{} `json:"states"`
 	// Globals holds workflow-level variables that persist throughout execution
 	Globals map[string]interface{} `json:"globals"`
+	// Locals holds variables scoped to a part of the execution, such as the iterationParam of a foreach state.
+	// They are exposed as top-level keys for JQ evaluation but never shadow the keys above.
+	Locals map[string]interface{} `json:"-"`
+	// Error holds the error caught by the onErrors of the previous state, so that the handler state can
+	// inspect it at .error
+	Error map[string]interface{} `json:"error,omitempty"`
 }
 
 // NewWorkflowData creates a new WorkflowData instance
@@ -27,10 +33,98 @@ func NewWorkflowData(input interface{}, globals map[string]interface{}) *Workflo
 
 // ToMap converts WorkflowData to a map for JQ evaluation
 func (w *WorkflowData) ToMap() map[string]interface{} {
-	return map[string]interface{}{
+	m := map[string]interface{}{
 		"initial": w.Initial,
 		"current": w.Current,
 		"states":  w.States,
 		"globals": w.Globals,
 	}
+	if w.Error != nil {
+		m["error"] = w.Error
+	}
+	for k, v := range w.Locals {
+		if _, reserved := m[k]; !reserved {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// clone returns a deep copy of the workflow data that can be used from another goroutine,
+// e.g. by a parallel branch. gojq normalizes its input in place, so concurrent evaluations
+// must never share maps or slices.
+func (w *WorkflowData) clone() *WorkflowData {
+	clone := &WorkflowData{
+		Initial: deepCopy(w.Initial),
+		Current: deepCopy(w.Current),
+		States:  deepCopyMap(w.States),
+		Globals: deepCopyMap(w.Globals),
+		Locals:  deepCopyMap(w.Locals),
+		Error:   deepCopyMap(w.Error),
+	}
+	return clone
+}
+
+// withLocal returns a copy of the workflow data with an additional local variable
+func (w *WorkflowData) withLocal(name string, value interface{}) *WorkflowData {
+	clone := w.clone()
+	if clone.Locals == nil {
+		clone.Locals = make(map[string]interface{}, 1)
+	}
+	clone.Locals[name] = deepCopy(value)
+	return clone
+}
+
+// mergeData deep merges src into dst. Objects are merged key by key, any other
+// value in src replaces the value in dst. Neither input is modified.
+func mergeData(dst, src interface{}) interface{} {
+	dstMap, dstIsMap := dst.(map[string]interface{})
+	srcMap, srcIsMap := src.(map[string]interface{})
+	if !dstIsMap || !srcIsMap {
+		return src
+	}
+
+	merged := make(map[string]interface{}, len(dstMap)+len(srcMap))
+	for k, v := range dstMap {
+		merged[k] = v
+	}
+	for k, v := range srcMap {
+		if existing, ok := merged[k]; ok {
+			merged[k] = mergeData(existing, v)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// deepCopy recursively copies JSON objects and arrays. Any other value is returned as is.
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		copied := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			copied[k] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// deepCopyMap is deepCopy for JSON objects
+func deepCopyMap(m map[string]interface{}) map[string]interface{} {
+	return deepCopy(m).(map[string]interface{})
 }
@@ -6,6 +6,7 @@ import (
 
 // ExecutionResult contains the final result and execution details
 type ExecutionResult struct {
+	RunID    string          `json:"runId"`
 	Data     interface{}     `json:"data"`
 	Debug    *ExecutionDebug `json:"debug,omitempty"`
 	Duration time.Duration   `json:"duration"`
@@ -18,24 +19,54 @@ type ExecutionDebug struct {
 
 // StateExecution represents a single state execution
 type StateExecution struct {
-	Name             string         `json:"name"`
-	Type             string         `json:"type"`
-	StartTime        time.Time      `json:"startTime"`
-	EndTime          time.Time      `json:"endTime"`
-	Input            interface{}    `json:"input,omitempty"`
-	Output           interface{}    `json:"output,omitempty"`
-	Error            string         `json:"error,omitempty"`
-	Actions          []ActionResult `json:"actions,omitempty"`
-	MatchedCondition string         `json:"matchedCondition,omitempty"` // For switch states
-	SleepDuration    string         `json:"sleepDuration,omitempty"`    // For sleep states
+	Name             string           `json:"name"`
+	Type             string           `json:"type"`
+	StartTime        time.Time        `json:"startTime"`
+	EndTime          time.Time        `json:"endTime"`
+	Input            interface{}      `json:"input,omitempty"`
+	Output           interface{}      `json:"output,omitempty"`
+	Error            string           `json:"error,omitempty"`
+	Actions          []ActionResult   `json:"actions,omitempty"`
+	MatchedCondition string           `json:"matchedCondition,omitempty"` // For switch states
+	SleepDuration    string           `json:"sleepDuration,omitempty"`    // For sleep states
+	Branches         []StateExecution `json:"branches,omitempty"`         // For parallel states
+	Iterations       []StateExecution `json:"iterations,omitempty"`       // For foreach states
+	Events           []ConsumedEvent  `json:"events,omitempty"`           // For event states and event-based switch states
+	CompensationFor  string           `json:"compensationFor,omitempty"`  // For compensation states, the state being compensated
+}
+
+// ConsumedEvent represents an event consumed by a state
+type ConsumedEvent struct {
+	EventRef string      `json:"eventRef"`
+	ID       string      `json:"id,omitempty"`
+	Source   string      `json:"source,omitempty"`
+	Type     string      `json:"type"`
+	Time     time.Time   `json:"time,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
 }
 
 // ActionResult represents the result of a single action execution
 type ActionResult struct {
-	ActivityName string      `json:"activityName"`
-	Arguments    interface{} `json:"arguments"`
-	StartTime    time.Time   `json:"startTime"`
-	EndTime      time.Time   `json:"endTime"`
-	Output       interface{} `json:"output"`
-	Error        string      `json:"error,omitempty"`
+	ActivityName string          `json:"activityName"`
+	Arguments    interface{}     `json:"arguments"`
+	StartTime    time.Time       `json:"startTime"`
+	EndTime      time.Time       `json:"endTime"`
+	Output       interface{}     `json:"output"`
+	Error        string          `json:"error,omitempty"`
+	Attempts     []ActionAttempt `json:"attempts,omitempty"` // For actions with a retry policy
+	Skipped      bool            `json:"skipped,omitempty"`  // The action's condition was not true, so it did not run
+	// For sub-flow actions, the invoked workflow, its run and, for sync sub-flows, its execution trace
+	WorkflowID      string          `json:"workflowId,omitempty"`
+	WorkflowVersion string          `json:"workflowVersion,omitempty"`
+	RunID           string          `json:"runId,omitempty"`
+	SubFlow         *ExecutionDebug `json:"subFlow,omitempty"`
+}
+
+// ActionAttempt represents a single attempt of an action executed with a retry policy
+type ActionAttempt struct {
+	Attempt   int       `json:"attempt"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Error     string    `json:"error,omitempty"`
+	Delay     string    `json:"delay,omitempty"` // Backoff before the next attempt
 }
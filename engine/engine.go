@@ -6,32 +6,82 @@ import (
 	"time"
 
 	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/events"
 	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/persistence"
 	"github.com/kshitiz1403/jsonjuggler/telemetry"
 	sw "github.com/serverlessworkflow/sdk-go/v2/model"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// Engine executes serverless workflows
+// Engine executes serverless workflows. It holds no per-run state, so a single Engine can
+// execute any number of workflows concurrently.
 type Engine struct {
 	registry     *activities.Registry
-	workflow     *sw.Workflow
 	debugEnabled bool
-	currentDebug *ExecutionDebug
 	logger       logger.Logger
-	statesMemo   map[string]sw.State
 	telemetry    *telemetry.Telemetry
+	store        persistence.Store
+	eventBus     events.Bus
+	callbacks    *callbacks
+	active       *activeRuns
+	workflows    *WorkflowRegistry
+	schemas      *schemas
+	// substringErrorMatching also matches onErrors references contained in the error message
+	substringErrorMatching bool
+}
+
+// Option configures optional features of an Engine
+type Option func(*Engine)
+
+// WithStore makes the engine checkpoint every run to the store, so that runs can be resumed
+func WithStore(store persistence.Store) Option {
+	return func(e *Engine) {
+		e.store = store
+	}
+}
+
+// WithEventBus sets the bus event states and event-based switch states consume events from
+func WithEventBus(bus events.Bus) Option {
+	return func(e *Engine) {
+		e.eventBus = bus
+	}
+}
+
+// WithWorkflowRegistry sets the registry of workflows executed by ID and resolved as sub-flows, so that engines
+// can share their workflows
+func WithWorkflowRegistry(registry *WorkflowRegistry) Option {
+	return func(e *Engine) {
+		e.workflows = registry
+	}
+}
+
+// WithSubstringErrorMatching makes onErrors references that match no error definition fall back to matching
+// any error whose message contains the reference, e.g. "connection refused"
+func WithSubstringErrorMatching() Option {
+	return func(e *Engine) {
+		e.substringErrorMatching = true
+	}
 }
 
 // NewEngine creates a new workflow engine
-func NewEngine(registry *activities.Registry, debugEnabled bool, log logger.Logger, tel *telemetry.Telemetry) *Engine {
-	return &Engine{
+func NewEngine(registry *activities.Registry, debugEnabled bool, log logger.Logger, tel *telemetry.Telemetry, opts ...Option) *Engine {
+	e := &Engine{
 		registry:     registry,
 		debugEnabled: debugEnabled,
 		logger:       log,
 		telemetry:    tel,
+		callbacks:    newCallbacks(),
+		active:       newActiveRuns(),
+		workflows:    NewWorkflowRegistry(),
+		schemas:      newSchemas(),
+	}
+	for _, opt := range opts {
+		opt(e)
 	}
+	return e
 }
 
 // GetRegistry returns the activity registry
@@ -39,8 +89,130 @@ func (e *Engine) GetRegistry() *activities.Registry {
 	return e.registry
 }
 
-// Execute runs a workflow with the given input
-func (e *Engine) Execute(ctx context.Context, workflow *ServerlessWorkflow, input interface{}, globals map[string]interface{}) (executionResult *ExecutionResult, err error) {
+// GetWorkflowRegistry returns the registry of workflows executed by ID and resolved as sub-flows
+func (e *Engine) GetWorkflowRegistry() *WorkflowRegistry {
+	return e.workflows
+}
+
+// LoadWorkflows parses the workflow definitions in the directory, validates them against the engine's activities
+// and registers them. A definition that references an unregistered activity or sub-flow, or duplicates a
+// registered version, fails the whole load.
+func (e *Engine) LoadWorkflows(dir string) error {
+	workflows, err := parser.NewParser(e.registry).ParseDir(dir)
+	if err != nil {
+		return NewWorkflowError(ErrWorkflowInvalid, "failed to load workflows").
+			WithContext("dir", dir).
+			WithCause(err)
+	}
+	if err := e.workflows.Load(workflows...); err != nil {
+		return err
+	}
+
+	e.logger.Infof("Loaded %d workflows from %s", len(workflows), dir)
+	return nil
+}
+
+// WatchWorkflows loads the workflow definitions in the directory like LoadWorkflows, then keeps polling the
+// directory at the interval until the context is done. Modified files are re-parsed, re-validated and swapped
+// into the workflow registry, so new executions use the new definition while running ones keep the old one. The
+// JSON Schemas the workflows reference are compiled again after every reload.
+// Invalid edits are logged and rejected, keeping the last valid definition of the file.
+func (e *Engine) WatchWorkflows(ctx context.Context, dir string, interval time.Duration) error {
+	watcher := parser.NewParser(e.registry).NewWatcher(dir)
+
+	// The registry is loaded from the watcher's initial snapshot, so that the first poll only reports the files
+	// that changed since the definitions were registered
+	var loaded int
+	var registerErr error
+	err := watcher.Load(func(workflows []*ServerlessWorkflow) error {
+		loaded = len(workflows)
+		registerErr = e.workflows.Load(workflows...)
+		return registerErr
+	})
+	if registerErr != nil {
+		return registerErr
+	}
+	if err != nil {
+		return NewWorkflowError(ErrWorkflowInvalid, "failed to load workflows").
+			WithContext("dir", dir).
+			WithCause(err)
+	}
+	e.logger.InfoContextf(ctx, "Loaded %d workflows from %s, watching for changes every %v", loaded, dir, interval)
+
+	go watcher.Watch(ctx, interval, func(change parser.Change) error {
+		return e.reloadWorkflow(ctx, change)
+	})
+	return nil
+}
+
+// reloadWorkflow swaps the changed definition of a watched workflow file into the workflow registry
+func (e *Engine) reloadWorkflow(ctx context.Context, change parser.Change) error {
+	if change.Err != nil {
+		e.logger.ErrorContextf(ctx, "Rejected workflow file %s: %v", change.Path, change.Err)
+		return change.Err
+	}
+	if err := e.workflows.Replace(change.Previous, change.Workflow); err != nil {
+		e.logger.ErrorContextf(ctx, "Rejected workflow file %s: %v", change.Path, err)
+		return err
+	}
+	// The schemas the definition references may have been edited along with it
+	e.schemas.reset()
+
+	if change.Workflow == nil {
+		e.logger.InfoContextf(ctx, "Unregistered workflow '%s' version '%s', %s was removed", change.Previous.ID, change.Previous.Version, change.Path)
+	} else {
+		e.logger.InfoContextf(ctx, "Reloaded workflow '%s' version '%s' from %s", change.Workflow.ID, change.Workflow.Version, change.Path)
+	}
+	return nil
+}
+
+// ExecuteByID runs the registered workflow with the given ID and version. Without a version the latest
+// registered version is executed.
+func (e *Engine) ExecuteByID(ctx context.Context, id, version string, input interface{}, globals map[string]interface{}) (*ExecutionResult, error) {
+	workflow, ok := e.workflows.Get(id, version)
+	if !ok {
+		e.logger.ErrorContextf(ctx, "Workflow '%s' version '%s' not found", id, version)
+		return nil, NewWorkflowError(ErrWorkflowNotFound, fmt.Sprintf("workflow '%s' is not registered", id)).
+			WithWorkflow(id).
+			WithContext("version", version)
+	}
+	return e.Execute(ctx, workflow, input, globals)
+}
+
+// Execute runs a workflow with the given input. The input is validated against the workflow's dataInputSchema,
+// if it declares one.
+func (e *Engine) Execute(ctx context.Context, workflow *ServerlessWorkflow, input interface{}, globals map[string]interface{}) (*ExecutionResult, error) {
+	if workflow == nil {
+		e.logger.ErrorContext(ctx, "Workflow cannot be nil")
+		return nil, NewWorkflowError(ErrWorkflowInvalid, "workflow cannot be nil")
+	}
+	if err := e.validateInput(ctx, workflow, input); err != nil {
+		return nil, err
+	}
+
+	// Initialize workflow data. Callers may share input and globals between concurrent executions,
+	// so every execution works on its own copy.
+	workflowData := NewWorkflowData(deepCopy(input), deepCopyMap(globals))
+
+	// Run IDs are random, so a new run cannot be running already
+	runID := newRunID()
+	e.active.start(runID)
+	defer e.active.finish(runID)
+
+	return e.run(ctx, newExecution(runID, workflow, e.debugEnabled), workflowData, startStateName(workflow))
+}
+
+// startStateName returns the name of the workflow's start state, or an empty name if it has none
+func startStateName(workflow *ServerlessWorkflow) string {
+	if workflow.Start == nil {
+		return ""
+	}
+	return workflow.Start.StateName
+}
+
+// run executes the workflow from the given state until it reaches an end state
+func (e *Engine) run(ctx context.Context, exec *execution, workflowData *WorkflowData, startState string) (executionResult *ExecutionResult, err error) {
+	runID, workflow := exec.runID, exec.workflow
 	if e.telemetry != nil {
 		var span trace.Span
 		ctx, span = e.telemetry.StartWorkflowSpan(ctx, workflow.ID)
@@ -55,25 +227,19 @@ func (e *Engine) Execute(ctx context.Context, workflow *ServerlessWorkflow, inpu
 		}()
 	}
 
-	executionResult = &ExecutionResult{}
+	executionResult = &ExecutionResult{RunID: runID}
 	startTime := time.Now()
 
-	if workflow == nil {
-		e.logger.ErrorContext(ctx, "Workflow cannot be nil")
-		return nil, NewWorkflowError(ErrWorkflowInvalid, "workflow cannot be nil")
-	}
-
 	if workflow.ID != "" {
 		// Add workflow ID to context for logging
 		ctx = context.WithValue(ctx, logger.WorkflowIDKey, workflow.ID)
 	}
 
-	e.logger.InfoContextf(ctx, "Starting workflow execution. ID: %s", workflow.ID)
+	e.logger.InfoContextf(ctx, "Starting workflow execution. ID: %s, run: %s", workflow.ID, runID)
 
+	ctx = withExecution(ctx, exec)
+	defer close(exec.done)
 	if e.debugEnabled {
-		e.currentDebug = &ExecutionDebug{
-			States: make([]StateExecution, 0),
-		}
 		e.logger.DebugContext(ctx, "Debug mode enabled")
 	}
 
@@ -87,7 +253,7 @@ func (e *Engine) Execute(ctx context.Context, workflow *ServerlessWorkflow, inpu
 
 		executionResult.Duration = executionTime
 		if e.debugEnabled {
-			executionResult.Debug = e.currentDebug
+			executionResult.Debug = exec.debug
 		}
 
 		// Record workflow duration
@@ -101,39 +267,92 @@ func (e *Engine) Execute(ctx context.Context, workflow *ServerlessWorkflow, inpu
 		}
 	}()
 
-	e.workflow = workflow
-
 	if workflow.Start == nil {
 		e.logger.ErrorContext(ctx, "Workflow must have a start state")
 		return executionResult, fmt.Errorf("workflow must have a start state")
 	}
 
-	// Initialize workflow data
-	workflowData := NewWorkflowData(input, globals)
-	e.logger.DebugContextf(ctx, "Initialized workflow data with input: %+v", input)
+	e.logger.DebugContextf(ctx, "Initialized workflow data with input: %+v", workflowData.Current)
 
-	// Set initial data
-	workflowData.Initial = input
+	execTimeout, timeout, err := workflowTimeout(workflow)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Invalid workflow timeout: %v", err)
+		return executionResult, err
+	}
+	workflowCtx, cancel := withTimeout(ctx, workflowExecTimeout, timeout)
+	defer cancel()
+	// Without interrupt the running state is allowed to finish and the timeout is enforced between states
+	stateCtx := ctx
+	if execTimeout != nil && execTimeout.Interrupt {
+		stateCtx = workflowCtx
+	}
 
-	state := e.findState(workflow, workflow.Start.StateName)
+	state := exec.findState(startState)
 	if state == nil {
-		e.logger.ErrorContextf(ctx, "Start state '%s' not found", workflow.Start.StateName)
-		return executionResult, NewWorkflowError(ErrStateNotFound, fmt.Sprintf("start state '%s' not found", workflow.Start.StateName))
+		e.logger.ErrorContextf(ctx, "Start state '%s' not found", startState)
+		return executionResult, NewWorkflowError(ErrStateNotFound, fmt.Sprintf("start state '%s' not found", startState))
+	}
+
+	if err := e.checkpoint(ctx, exec, workflowData, state.GetName(), nil); err != nil {
+		return executionResult, err
 	}
 
 	for state != nil {
+		if err := ctx.Err(); err != nil {
+			e.logger.ErrorContextf(ctx, "Workflow cancelled before state %s: %v", state.GetName(), err)
+			return executionResult, NewWorkflowError(ErrWorkflowCancel, "workflow cancelled").
+				WithWorkflow(workflow.ID).
+				WithState(state.GetName()).
+				WithCause(err)
+		}
+		if timeout := expiredTimeout(workflowCtx); timeout != nil {
+			err := e.handleWorkflowTimeout(ctx, exec, execTimeout, workflowData, timeout, nil)
+			e.compensateFailedRun(ctx, exec, workflowData)
+			executionResult.Data = workflowData.Current
+			return executionResult, e.failRun(ctx, exec, workflowData, state.GetName(), err)
+		}
+
 		e.logger.InfoContextf(ctx, "Executing state: %s (Type: %s)", state.GetName(), state.GetType())
 
-		stateResult, err := e.executeState(ctx, state, workflowData)
+		stateResult, err := e.executeState(stateCtx, state, workflowData)
 		if err != nil {
+			if timeout := expiredTimeout(workflowCtx); timeout != nil && ctx.Err() == nil {
+				err = e.handleWorkflowTimeout(ctx, exec, execTimeout, workflowData, timeout, err)
+				e.compensateFailedRun(ctx, exec, workflowData)
+				executionResult.Data = workflowData.Current
+				return executionResult, e.failRun(ctx, exec, workflowData, state.GetName(), err)
+			}
 			e.logger.ErrorContextf(ctx, "Error executing state %s: %v", state.GetName(), err)
-			return executionResult, NewWorkflowError(ErrStateExecutionFail, fmt.Errorf("error executing state %s: %w", state.GetName(), err).Error()).WithCause(err)
+			err = NewWorkflowError(ErrStateExecutionFail, fmt.Errorf("error executing state %s: %w", state.GetName(), err).Error()).WithCause(err)
+			e.compensateFailedRun(ctx, exec, workflowData)
+			return executionResult, e.failRun(ctx, exec, workflowData, state.GetName(), err)
+		}
+
+		if stateResult.Error == nil && state.GetCompensatedBy() != "" {
+			exec.compensable = append(exec.compensable, state.GetName())
 		}
 
 		// Store state result in the States map
 		workflowData.States[state.GetName()] = stateResult.Data
 		// Update current data
 		workflowData.Current = stateResult.Data
+		// An error caught by onErrors is only visible to the handler state
+		workflowData.Error = nil
+		if stateResult.Error != nil {
+			workflowData.Error = errorData(stateResult.Error)
+		}
+
+		if stateResult.Compensate {
+			if err := e.compensate(ctx, exec, workflowData); err != nil {
+				e.logger.ErrorContextf(ctx, "Compensation failed: %v", err)
+				executionResult.Data = workflowData.Current
+				return executionResult, e.failRun(ctx, exec, workflowData, state.GetName(), err)
+			}
+		}
+
+		if err := e.checkpoint(ctx, exec, workflowData, stateResult.NextState, nil); err != nil {
+			return executionResult, err
+		}
 
 		if stateResult.NextState == "" {
 			e.logger.InfoContext(ctx, "Workflow execution completed - reached end state")
@@ -141,11 +360,13 @@ func (e *Engine) Execute(ctx context.Context, workflow *ServerlessWorkflow, inpu
 		}
 
 		e.logger.DebugContextf(ctx, "Transitioning from state '%s' to '%s'", state.GetName(), stateResult.NextState)
-		state = e.findState(workflow, stateResult.NextState)
+		state = exec.findState(stateResult.NextState)
 		if state == nil {
 			err := NewWorkflowError(ErrStateTransitionFail, fmt.Sprintf("transition state %s not found", stateResult.NextState))
 			e.logger.ErrorContext(ctx, err)
-			return executionResult, err
+			e.compensateFailedRun(ctx, exec, workflowData)
+			executionResult.Data = workflowData.Current
+			return executionResult, e.failRun(ctx, exec, workflowData, stateResult.NextState, err)
 		}
 	}
 
@@ -154,17 +375,30 @@ func (e *Engine) Execute(ctx context.Context, workflow *ServerlessWorkflow, inpu
 	return executionResult, nil
 }
 
-func (e *Engine) findState(workflow *ServerlessWorkflow, name string) sw.State {
-	if e.statesMemo == nil {
-		e.statesMemo = make(map[string]sw.State)
-		for _, state := range workflow.States {
-			e.statesMemo[state.GetName()] = state
-		}
+// handleWorkflowTimeout runs the workflowExecTimeout's runBefore state, if any, and returns the timeout error.
+// The runBefore state is executed with a context that is not bound by the expired timeout, and its output
+// becomes the workflow data.
+func (e *Engine) handleWorkflowTimeout(ctx context.Context, exec *execution, execTimeout *sw.WorkflowExecTimeout, data *WorkflowData, timeout *timeoutCause, cause error) error {
+	timeoutErr := timeout.newError(cause).WithWorkflow(exec.workflow.ID)
+	e.logger.ErrorContextf(ctx, "Workflow timed out: %v", timeoutErr)
+
+	if execTimeout.RunBefore == "" {
+		return timeoutErr
 	}
 
-	if state, ok := e.statesMemo[name]; ok {
-		return state
+	state := exec.findState(execTimeout.RunBefore)
+	if state == nil {
+		e.logger.ErrorContextf(ctx, "RunBefore state '%s' not found", execTimeout.RunBefore)
+		return timeoutErr
 	}
 
-	return nil
+	e.logger.InfoContextf(ctx, "Executing runBefore state: %s", state.GetName())
+	result, err := e.executeState(context.WithoutCancel(ctx), state, data)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "RunBefore state %s failed: %v", state.GetName(), err)
+		return timeoutErr
+	}
+	data.States[state.GetName()] = result.Data
+	data.Current = result.Data
+	return timeoutErr
 }
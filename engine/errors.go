@@ -1,10 +1,15 @@
 package engine
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+	"github.com/spf13/cast"
 )
 
 // ErrorCode represents a unique identifier for each type of error
@@ -12,24 +17,39 @@ type ErrorCode string
 
 const (
 	// Workflow Errors
-	ErrWorkflowInvalid ErrorCode = "WORKFLOW_INVALID"
+	ErrWorkflowInvalid  ErrorCode = "WORKFLOW_INVALID"
+	ErrRetryNotFound    ErrorCode = "RETRY_NOT_FOUND"
+	ErrWorkflowCancel   ErrorCode = "WORKFLOW_CANCELLED"
+	ErrRunNotFound      ErrorCode = "RUN_NOT_FOUND"
+	ErrPersistence      ErrorCode = "PERSISTENCE_FAILED"
+	ErrWorkflowNotFound ErrorCode = "WORKFLOW_NOT_FOUND"
+	ErrSubFlowExecution ErrorCode = "SUBFLOW_EXECUTION_FAILED"
 
 	// State Errors
 	ErrStateNotFound       ErrorCode = "STATE_NOT_FOUND"
 	ErrStateInvalid        ErrorCode = "STATE_INVALID"
 	ErrStateExecutionFail  ErrorCode = "STATE_EXECUTION_FAILED"
 	ErrStateTransitionFail ErrorCode = "STATE_TRANSITION_FAILED"
+	ErrCompensation        ErrorCode = "COMPENSATION_FAILED"
 
 	// Activity Errors
 	ErrActivityNotFound   ErrorCode = "ACTIVITY_NOT_FOUND"
 	ErrActivityArgInvalid ErrorCode = "ACTIVITY_ARGS_INVALID"
 	ErrActivityExecution  ErrorCode = "ACTIVITY_EXECUTION_FAILED"
+	ErrFunctionNotFound   ErrorCode = "FUNCTION_NOT_FOUND"
 
 	// Data Errors
 	ErrDataTransform      ErrorCode = "DATA_TRANSFORM_FAILED"
 	ErrDataValidation     ErrorCode = "DATA_VALIDATION_FAILED"
 	ErrDataTypeConversion ErrorCode = "DATA_TYPE_CONVERSION_FAILED"
 
+	// Timeout Errors
+	ErrTimeout ErrorCode = "TIMEOUT"
+
+	// Event Errors
+	ErrEventNotFound ErrorCode = "EVENT_NOT_FOUND"
+	ErrEventBus      ErrorCode = "EVENT_BUS_FAILED"
+
 	// Expression Errors
 	ErrExpressionInvalid ErrorCode = "EXPRESSION_INVALID"
 	ErrExpressionEval    ErrorCode = "EXPRESSION_EVAL_FAILED"
@@ -88,6 +108,11 @@ func (e *WorkflowError) Error() string {
 	return msg
 }
 
+// Unwrap returns the underlying cause so the error chain can be inspected with errors.Is and errors.As
+func (e *WorkflowError) Unwrap() error {
+	return e.Cause
+}
+
 // NewWorkflowError creates a new WorkflowError with the given code and message
 func NewWorkflowError(code ErrorCode, message string) *WorkflowError {
 	return &WorkflowError{
@@ -115,6 +140,12 @@ func (e *WorkflowError) WithWorkflow(workflowID string) *WorkflowError {
 	return e
 }
 
+// WithExpression adds the expression that failed to the error
+func (e *WorkflowError) WithExpression(expression string) *WorkflowError {
+	e.Context.Expression = expression
+	return e
+}
+
 // WithCause adds an underlying cause to the error
 func (e *WorkflowError) WithCause(err error) *WorkflowError {
 	e.Cause = err
@@ -171,3 +202,130 @@ func NewWorkflowErrorFromActivity(code ErrorCode, message string, actErr *activi
 		Cause: actErr,
 	}
 }
+
+// matchesErrorRef reports whether err matches the workflow error definition referenced by ref.
+// The reference is resolved against the workflow's declared errors; an undeclared reference is used as the
+// code itself. The code is matched as follows:
+//   - a JQ predicate, e.g. "${ .statusCode >= 500 }", is evaluated against the structured error
+//   - a number is compared with the HTTP status code reported by the activity
+//   - anything else is compared with the ActivityError and WorkflowError codes in the error chain
+//
+// A declared definition without a code is matched by its name.
+func matchesErrorRef(workflow *ServerlessWorkflow, err error, ref string) bool {
+	code := ref
+	if definition, ok := findErrorDefinition(workflow, ref); ok && definition.Code != "" {
+		code = definition.Code
+	}
+
+	if utils.IsValidJQTemplate(code) {
+		matched, evalErr := utils.EvaluateExpression(code, errorData(err))
+		return evalErr == nil && matched == true
+	}
+
+	if status, convErr := strconv.Atoi(code); convErr == nil {
+		statusCode, ok := httpStatusCode(err)
+		return ok && statusCode == status
+	}
+
+	if actErr, ok := UnwrapActivityError(err); ok && string(actErr.Code) == code {
+		return true
+	}
+
+	var wfErr *WorkflowError
+	for current := err; errors.As(current, &wfErr); current = wfErr.Cause {
+		if string(wfErr.Code) == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findErrorDefinition returns the workflow's error definition with the given name
+func findErrorDefinition(workflow *ServerlessWorkflow, name string) (sw.Error, bool) {
+	if workflow == nil {
+		return sw.Error{}, false
+	}
+	for _, definition := range workflow.Errors {
+		if definition.Name == name {
+			return definition, true
+		}
+	}
+	return sw.Error{}, false
+}
+
+// httpStatusCode returns the HTTP status code an activity reported in its error arguments
+func httpStatusCode(err error) (int, bool) {
+	actErr, ok := UnwrapActivityError(err)
+	if !ok || actErr.Arguments == nil {
+		return 0, false
+	}
+	statusCode, found := actErr.Arguments["statusCode"]
+	if !found {
+		return 0, false
+	}
+	status, convErr := cast.ToIntE(statusCode)
+	return status, convErr == nil
+}
+
+// errorData returns the structured, JSON compatible representation of an error: its code, message,
+// state, activity, arguments and cause chain, plus the HTTP status code when an activity reported one
+func errorData(err error) map[string]interface{} {
+	data := describeError(err)
+	if statusCode, ok := httpStatusCode(err); ok {
+		data["statusCode"] = statusCode
+	}
+
+	raw, marshalErr := json.Marshal(data)
+	if marshalErr != nil {
+		return map[string]interface{}{"message": err.Error()}
+	}
+	var normalized map[string]interface{}
+	if unmarshalErr := json.Unmarshal(raw, &normalized); unmarshalErr != nil {
+		return map[string]interface{}{"message": err.Error()}
+	}
+	return normalized
+}
+
+// describeError converts an error and its causes into nested maps
+func describeError(err error) map[string]interface{} {
+	data := map[string]interface{}{"message": err.Error()}
+	var cause error
+
+	switch typed := err.(type) {
+	case *WorkflowError:
+		data["code"] = string(typed.Code)
+		data["message"] = typed.Message
+		if typed.Context.StateName != "" {
+			data["state"] = typed.Context.StateName
+		}
+		if typed.Context.ActivityName != "" {
+			data["activity"] = typed.Context.ActivityName
+		}
+		if typed.Context.Expression != "" {
+			data["expression"] = typed.Context.Expression
+		}
+		if len(typed.Context.Arguments) > 0 {
+			data["arguments"] = typed.Context.Arguments
+		}
+		if len(typed.Context.AdditionalInfo) > 0 {
+			data["details"] = typed.Context.AdditionalInfo
+		}
+		cause = typed.Cause
+	case *activities.ActivityError:
+		data["code"] = string(typed.Code)
+		data["message"] = typed.Message
+		data["activity"] = typed.ActivityName
+		if len(typed.Arguments) > 0 {
+			data["arguments"] = typed.Arguments
+		}
+		cause = typed.Cause
+	default:
+		cause = errors.Unwrap(err)
+	}
+
+	if cause != nil {
+		data["cause"] = describeError(cause)
+	}
+	return data
+}
@@ -2,12 +2,424 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/kshitiz1403/jsonjuggler/events"
+	"github.com/kshitiz1403/jsonjuggler/utils"
 	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+	"github.com/spf13/cast"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// consumedEvent is an event consumed by a state, together with the event definition it was consumed for
+type consumedEvent struct {
+	ref        string
+	definition sw.Event
+	event      events.Event
+}
+
+// eventWaiter waits for the events referenced by a state. It only subscribes to the events it still waits for,
+// correlated with the pinned values, so that the bus keeps the other events for other waiters.
+type eventWaiter struct {
+	bus         events.Bus
+	sub         events.Subscription
+	refs        []string
+	filters     map[string]events.Filter
+	definitions map[string]sw.Event
+	// pinned holds the values of correlation attributes declared without a value, taken from the first
+	// consumed event, so that all events consumed by the state belong together
+	pinned map[string]string
+}
+
+// executeEventBasedSwitch waits for the first event matching one of the switch's event conditions and
+// transitions accordingly. If no event arrives within the eventTimeout the default condition is taken.
 func (e *Engine) executeEventBasedSwitch(ctx context.Context, state *sw.SwitchState, data *WorkflowData, stateExec *StateExecution) (*StateResult, error) {
-	// TODO: Implement event-based switching when needed
-	return nil, fmt.Errorf("event-based switch not implemented")
+	refs := make([]string, 0, len(state.EventConditions))
+	for _, condition := range state.EventConditions {
+		refs = append(refs, condition.EventRef)
+	}
+
+	consumed, err := e.waitForEvents(ctx, state, refs, data, false, stateExec)
+	if err != nil {
+		if isEventTimeout(err) {
+			e.logger.InfoContext(ctx, "No event received within the eventTimeout, taking default condition")
+			if stateExec != nil {
+				stateExec.MatchedCondition = "default"
+			}
+			return &StateResult{
+				Data:       data.Current,
+				NextState:  defaultConditionNextState(state.DefaultCondition),
+				Compensate: compensates(state.DefaultCondition.Transition, state.DefaultCondition.End),
+			}, nil
+		}
+		return nil, err
+	}
+
+	event := consumed[0]
+	for _, condition := range state.EventConditions {
+		if condition.EventRef != event.ref {
+			continue
+		}
+
+		output, err := e.applyEventDataFilter(ctx, condition.EventDataFilter, data.Current, event)
+		if err != nil {
+			return nil, err
+		}
+
+		if stateExec != nil {
+			stateExec.MatchedCondition = condition.Name
+			if condition.Name == "" {
+				stateExec.MatchedCondition = condition.EventRef
+			}
+		}
+
+		var nextState string
+		if condition.Transition != nil {
+			nextState = condition.Transition.NextState
+		}
+		return &StateResult{
+			Data:       output,
+			NextState:  nextState,
+			Compensate: compensates(condition.Transition, condition.End),
+		}, nil
+	}
+
+	// waitForEvents only returns events for the given refs
+	return nil, fmt.Errorf("no event condition for event '%s'", event.ref)
+}
+
+// executeEventState waits for the events of the state's onEvents definitions and performs their actions.
+// An exclusive state performs the actions of the first event that arrives, otherwise all the events must
+// arrive before the actions of every onEvents definition are performed in order.
+func (e *Engine) executeEventState(ctx context.Context, state *sw.EventState, data *WorkflowData, stateExec *StateExecution) (*StateResult, error) {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, onEvents := range state.OnEvents {
+		for _, ref := range onEvents.EventRefs {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	consumed, err := e.waitForEvents(ctx, state, refs, data, !state.Exclusive, stateExec)
+	if err != nil {
+		return nil, err
+	}
+
+	byRef := make(map[string]consumedEvent, len(consumed))
+	for _, event := range consumed {
+		byRef[event.ref] = event
+	}
+
+	for _, onEvents := range state.OnEvents {
+		var matched []consumedEvent
+		for _, ref := range onEvents.EventRefs {
+			if event, ok := byRef[ref]; ok {
+				matched = append(matched, event)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		if err := e.executeOnEvents(ctx, onEvents, matched, data, stateExec); err != nil {
+			return nil, err
+		}
+
+		if state.Exclusive {
+			break
+		}
+	}
+
+	var nextState string
+	if state.GetTransition() != nil {
+		nextState = state.GetTransition().NextState
+	}
+
+	return &StateResult{
+		Data:      data.Current,
+		NextState: nextState,
+	}, nil
+}
+
+// executeOnEvents merges the consumed events into the state data and performs the onEvents actions
+func (e *Engine) executeOnEvents(ctx context.Context, onEvents sw.OnEvents, consumed []consumedEvent, data *WorkflowData, stateExec *StateExecution) error {
+	for _, event := range consumed {
+		output, err := e.applyEventDataFilter(ctx, onEvents.EventDataFilter, data.Current, event)
+		if err != nil {
+			return err
+		}
+		data.Current = output
+	}
+
+	if len(onEvents.Actions) == 0 {
+		return nil
+	}
+
+	result, err := e.executeActionsInMode(ctx, onEvents.ActionMode, onEvents.Actions, data, stateExec)
+	if err != nil {
+		return err
+	}
+	data.Current = result.Data
+	return nil
+}
+
+// waitForEvents waits, at most for the state's eventTimeout, for the first event matching one of the refs,
+// or for an event for every ref if all is set. Consumed events are recorded in the debug data.
+func (e *Engine) waitForEvents(ctx context.Context, state sw.State, refs []string, data *WorkflowData, all bool, stateExec *StateExecution) (consumed []consumedEvent, err error) {
+	if e.telemetry != nil {
+		var eventSpan trace.Span
+		ctx, eventSpan = e.telemetry.StartEventWaitSpan(ctx, state.GetName(), refs)
+		defer func() {
+			if err != nil {
+				eventSpan.RecordError(err)
+				eventSpan.SetStatus(codes.Error, err.Error())
+			} else {
+				eventSpan.SetStatus(codes.Ok, "")
+			}
+			eventSpan.End()
+		}()
+	}
+
+	timeout, err := eventTimeout(workflowFromContext(ctx), state)
+	if err != nil {
+		return nil, err
+	}
+
+	waiter, err := e.newEventWaiter(ctx, state.GetName(), refs, data)
+	if err != nil {
+		return nil, err
+	}
+	defer waiter.close()
+
+	waitCtx, cancel := withTimeout(ctx, eventTimeoutName, timeout)
+	defer cancel()
+
+	pending := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		pending[ref] = true
+	}
+
+	e.logger.DebugContextf(ctx, "Waiting for events %v", refs)
+	for len(pending) > 0 {
+		event, err := waiter.next(waitCtx, pending)
+		if err != nil {
+			if timeout := expiredTimeout(waitCtx); timeout != nil {
+				err = timeout.newError(err).WithState(state.GetName())
+			}
+			e.logger.ErrorContextf(ctx, "Stopped waiting for events: %v", err)
+			return nil, err
+		}
+
+		e.logger.InfoContextf(ctx, "Consumed event '%s' of type %s", event.ref, event.event.Type)
+		if e.telemetry != nil {
+			e.telemetry.RecordEventConsumed(ctx, event.ref, event.event.Type)
+		}
+		if stateExec != nil {
+			stateExec.Events = append(stateExec.Events, ConsumedEvent{
+				EventRef: event.ref,
+				ID:       event.event.ID,
+				Source:   event.event.Source,
+				Type:     event.event.Type,
+				Time:     event.event.Time,
+				Data:     event.event.Data,
+			})
+		}
+
+		consumed = append(consumed, event)
+		delete(pending, event.ref)
+		if !all || len(pending) == 0 {
+			break
+		}
+		if err := waiter.subscribe(ctx, pending); err != nil {
+			return nil, NewWorkflowError(ErrEventBus, "Failed to subscribe to events").
+				WithState(state.GetName()).
+				WithCause(err)
+		}
+	}
+
+	return consumed, nil
+}
+
+// newEventWaiter subscribes to the events referenced by a state. Correlation values given as expressions
+// are evaluated against the workflow data, e.g. "${ .current.orderId }".
+func (e *Engine) newEventWaiter(ctx context.Context, stateName string, refs []string, data *WorkflowData) (*eventWaiter, error) {
+	if e.eventBus == nil {
+		return nil, NewWorkflowError(ErrEventBus, "no event bus configured").
+			WithState(stateName)
+	}
+
+	workflow := workflowFromContext(ctx)
+	waiter := &eventWaiter{
+		bus:         e.eventBus,
+		refs:        refs,
+		filters:     make(map[string]events.Filter, len(refs)),
+		definitions: make(map[string]sw.Event, len(refs)),
+		pinned:      make(map[string]string),
+	}
+
+	for _, ref := range refs {
+		definition, ok := findEventDefinition(workflow, ref)
+		if !ok {
+			return nil, NewWorkflowError(ErrEventNotFound, fmt.Sprintf("event '%s' is not defined", ref)).
+				WithState(stateName).
+				WithContext("eventRef", ref)
+		}
+
+		filter := events.Filter{
+			Type:        definition.Type,
+			Source:      definition.Source,
+			Correlation: make(map[string]string, len(definition.Correlation)),
+		}
+		for _, correlation := range definition.Correlation {
+			value := correlation.ContextAttributeValue
+			if utils.IsValidJQTemplate(value) {
+				evaluated, err := utils.EvaluateExpression(value, data.ToMap())
+				if err != nil {
+					return nil, NewWorkflowError(ErrExpressionEval, "Failed to evaluate correlation value").
+						WithState(stateName).
+						WithContext("eventRef", ref).
+						WithContext("contextAttributeName", correlation.ContextAttributeName).
+						WithCause(err)
+				}
+				value = cast.ToString(evaluated)
+			}
+			filter.Correlation[correlation.ContextAttributeName] = value
+		}
+
+		waiter.filters[ref] = filter
+		waiter.definitions[ref] = definition
+	}
+
+	if err := waiter.subscribe(ctx, nil); err != nil {
+		return nil, NewWorkflowError(ErrEventBus, "Failed to subscribe to events").
+			WithState(stateName).
+			WithCause(err)
+	}
+	return waiter, nil
+}
+
+// subscribe replaces the waiter's subscription with one to the pending refs, or to all refs if pending is nil.
+// The events the previous subscription did not deliver are handed back to the bus before subscribing again.
+func (w *eventWaiter) subscribe(ctx context.Context, pending map[string]bool) error {
+	w.close()
+	w.sub = nil
+
+	filters := make([]events.Filter, 0, len(w.refs))
+	for _, ref := range w.refs {
+		if pending == nil || pending[ref] {
+			filters = append(filters, w.filter(ref))
+		}
+	}
+	sub, err := w.bus.Subscribe(ctx, filters...)
+	if err != nil {
+		return err
+	}
+	w.sub = sub
+	return nil
+}
+
+// filter returns the filter of the ref, with the correlation values pinned by earlier events
+func (w *eventWaiter) filter(ref string) events.Filter {
+	filter := w.filters[ref]
+	correlation := make(map[string]string, len(filter.Correlation))
+	for name, value := range filter.Correlation {
+		if pinned, ok := w.pinned[name]; ok && value == "" {
+			value = pinned
+		}
+		correlation[name] = value
+	}
+	filter.Correlation = correlation
+	return filter
+}
+
+// next waits for an event matching one of the pending refs
+func (w *eventWaiter) next(ctx context.Context, pending map[string]bool) (consumedEvent, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return consumedEvent{}, ctx.Err()
+		case event := <-w.sub.Events():
+			for _, ref := range w.refs {
+				if pending[ref] && w.filter(ref).Matches(event) {
+					w.pin(ref, event)
+					return consumedEvent{ref: ref, definition: w.definitions[ref], event: event}, nil
+				}
+			}
+		}
+	}
+}
+
+// pin records the values of the correlation attributes that were declared without a value
+func (w *eventWaiter) pin(ref string, event events.Event) {
+	for name, value := range w.filters[ref].Correlation {
+		if _, ok := w.pinned[name]; !ok && value == "" {
+			w.pinned[name] = event.Extensions[name]
+		}
+	}
+}
+
+// close ends the subscription
+func (w *eventWaiter) close() {
+	if w.sub != nil {
+		w.sub.Close()
+	}
+}
+
+// payload returns the event as seen by the workflow: only its data for dataOnly events,
+// otherwise the data together with the event's context attributes
+func (c consumedEvent) payload() interface{} {
+	if c.definition.DataOnly {
+		return c.event.Data
+	}
+
+	payload := map[string]interface{}{
+		"id":     c.event.ID,
+		"source": c.event.Source,
+		"type":   c.event.Type,
+		"data":   c.event.Data,
+	}
+	if !c.event.Time.IsZero() {
+		payload["time"] = c.event.Time.Format(time.RFC3339Nano)
+	}
+	for name, value := range c.event.Extensions {
+		if _, reserved := payload[name]; !reserved {
+			payload[name] = value
+		}
+	}
+	return payload
+}
+
+// isEventTimeout reports whether err is the timeout error of an expired eventTimeout
+func isEventTimeout(err error) bool {
+	var wfErr *WorkflowError
+	return errors.As(err, &wfErr) && wfErr.Code == ErrTimeout &&
+		wfErr.Context.AdditionalInfo["timeout"] == eventTimeoutName
+}
+
+// findEventDefinition returns the workflow's event definition with the given name
+func findEventDefinition(workflow *ServerlessWorkflow, name string) (sw.Event, bool) {
+	if workflow == nil {
+		return sw.Event{}, false
+	}
+	for _, event := range workflow.Events {
+		if event.Name == name {
+			return event, true
+		}
+	}
+	return sw.Event{}, false
+}
+
+// defaultConditionNextState returns the state the default condition of a switch transitions to,
+// or an empty name if it ends the workflow
+func defaultConditionNextState(condition sw.DefaultCondition) string {
+	if condition.Transition == nil {
+		return ""
+	}
+	return condition.Transition.NextState
 }
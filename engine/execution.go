@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/persistence"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+)
+
+// executionKey is the context key under which the running execution is stored
+type executionKey struct{}
+
+// execution holds everything that belongs to a single run of a workflow, so that one Engine
+// can run many workflows concurrently
+type execution struct {
+	runID    string
+	workflow *ServerlessWorkflow
+	// states indexes the workflow's states by name
+	states map[string]sw.State
+	// compensable lists the completed states that define compensatedBy, in order of completion
+	compensable []string
+	// callback is the callback state a resumed run was waiting in, whose action is not performed again
+	callback *persistence.Callback
+
+	// done is closed when the run returns, terminating its async sub-flows
+	done chan struct{}
+
+	mu    sync.Mutex
+	debug *ExecutionDebug
+	// functions caches the workflow's functions resolved to their activities
+	functions map[string]*parser.Function
+
+	definitionOnce sync.Once
+	definitionJSON []byte
+	definitionErr  error
+}
+
+// newExecution creates the execution scoped state for a run of the workflow
+func newExecution(runID string, workflow *ServerlessWorkflow, debugEnabled bool) *execution {
+	exec := &execution{
+		runID:     runID,
+		workflow:  workflow,
+		states:    make(map[string]sw.State, len(workflow.States)),
+		done:      make(chan struct{}),
+		functions: make(map[string]*parser.Function),
+	}
+	for _, state := range workflow.States {
+		exec.states[state.GetName()] = state
+	}
+	if debugEnabled {
+		exec.debug = &ExecutionDebug{
+			States: make([]StateExecution, 0),
+		}
+	}
+	return exec
+}
+
+// activeRuns tracks the runs executing in an engine, so that a run is not resumed while it is still running
+type activeRuns struct {
+	mu   sync.Mutex
+	runs map[string]bool
+}
+
+func newActiveRuns() *activeRuns {
+	return &activeRuns{runs: make(map[string]bool)}
+}
+
+// start marks the run as executing. It returns false if the run already is.
+func (a *activeRuns) start(runID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.runs[runID] {
+		return false
+	}
+	a.runs[runID] = true
+	return true
+}
+
+// finish marks the run as no longer executing
+func (a *activeRuns) finish(runID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.runs, runID)
+}
+
+// newRunID returns a random identifier for a workflow run
+func newRunID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		// crypto/rand does not fail on supported platforms, fall back to the clock just in case
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
+
+// findState returns the state with the given name, or nil if the workflow does not define it
+func (x *execution) findState(name string) sw.State {
+	return x.states[name]
+}
+
+// definition returns the JSON definition of the workflow: the source it was parsed from, or for workflows without
+// one, the workflow serialized once per run
+func (x *execution) definition() ([]byte, error) {
+	if len(x.workflow.Source) > 0 {
+		return x.workflow.Source, nil
+	}
+	x.definitionOnce.Do(func() {
+		x.definitionJSON, x.definitionErr = json.Marshal(&x.workflow.Workflow)
+	})
+	return x.definitionJSON, x.definitionErr
+}
+
+// resumesCallback reports whether the run was resumed while it waited in the callback state
+func (x *execution) resumesCallback(stateName string) bool {
+	return x != nil && x.callback != nil && x.callback.State == stateName
+}
+
+// recordState appends a state execution to the debug data
+func (x *execution) recordState(stateExec StateExecution) {
+	if x.debug == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.debug.States = append(x.debug.States, stateExec)
+}
+
+// withExecution returns a context carrying the execution
+func withExecution(ctx context.Context, exec *execution) context.Context {
+	return context.WithValue(ctx, executionKey{}, exec)
+}
+
+// executionFromContext returns the execution the context belongs to, or nil outside of an execution
+func executionFromContext(ctx context.Context) *execution {
+	exec, _ := ctx.Value(executionKey{}).(*execution)
+	return exec
+}
+
+// workflowFromContext returns the workflow being executed, or nil outside of an execution
+func workflowFromContext(ctx context.Context) *ServerlessWorkflow {
+	if exec := executionFromContext(ctx); exec != nil {
+		return exec.workflow
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+)
+
+// applyStateInputFilter applies the input expression of the state's stateDataFilter. The expression is
+// evaluated against the workflow data, e.g. "${ .current.order }", and its result becomes the state data.
+func (e *Engine) applyStateInputFilter(ctx context.Context, state sw.State, data *WorkflowData) error {
+	filter := state.GetStateDataFilter()
+	if filter == nil || filter.Input == "" {
+		return nil
+	}
+
+	filtered, err := utils.EvaluateExpression(filter.Input, data.ToMap())
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to apply state input filter: %v", err)
+		return NewWorkflowError(ErrDataTransform, "Failed to apply state input filter").
+			WithState(state.GetName()).
+			WithContext("input", filter.Input).
+			WithCause(err)
+	}
+
+	e.logger.DebugContextf(ctx, "State input filtered to: %+v", filtered)
+	data.Current = filtered
+	return nil
+}
+
+// applyStateOutputFilter applies the output expression of the state's stateDataFilter to the state output.
+// The expression is evaluated against the workflow data with the state output as the current data.
+func (e *Engine) applyStateOutputFilter(ctx context.Context, state sw.State, data *WorkflowData, output interface{}) (interface{}, error) {
+	filter := state.GetStateDataFilter()
+	if filter == nil || filter.Output == "" {
+		return output, nil
+	}
+
+	outputData := *data
+	outputData.Current = output
+	filtered, err := utils.EvaluateExpression(filter.Output, outputData.ToMap())
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to apply state output filter: %v", err)
+		return nil, NewWorkflowError(ErrDataTransform, "Failed to apply state output filter").
+			WithState(state.GetName()).
+			WithContext("output", filter.Output).
+			WithCause(err)
+	}
+
+	e.logger.DebugContextf(ctx, "State output filtered to: %+v", filtered)
+	return filtered, nil
+}
+
+// actionInputData returns the workflow data an action resolves its arguments against. When the action's
+// actionDataFilter has a fromStateData expression, its result is exposed as the current data.
+func (e *Engine) actionInputData(ctx context.Context, action sw.Action, data *WorkflowData) (*WorkflowData, error) {
+	filter := action.ActionDataFilter
+	if filter.FromStateData == "" {
+		return data, nil
+	}
+
+	selected, err := utils.EvaluateExpression(filter.FromStateData, data.ToMap())
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to apply fromStateData filter: %v", err)
+		return nil, NewWorkflowError(ErrDataTransform, "Failed to apply action fromStateData filter").
+			WithActivity(actionName(action)).
+			WithContext("fromStateData", filter.FromStateData).
+			WithCause(err)
+	}
+
+	actionData := *data
+	actionData.Current = selected
+	return &actionData, nil
+}
+
+// applyActionResults returns the state data after an action completed. Without an actionDataFilter the
+// result replaces the state data. The results expression is evaluated against the raw action result, and
+// toStateData merges the (filtered) result into that path of the state data. With useResults set to false
+// the state data is left untouched.
+func (e *Engine) applyActionResults(ctx context.Context, action sw.Action, stateData interface{}, result interface{}) (interface{}, error) {
+	if action.SubFlowRef != nil && action.SubFlowRef.Invoke == sw.InvokeKindAsync {
+		e.logger.DebugContext(ctx, "Keeping state data, async sub-flows have no results")
+		return stateData, nil
+	}
+
+	filter := action.ActionDataFilter
+	if !filter.UseResults {
+		e.logger.DebugContext(ctx, "Discarding action results, useResults is false")
+		return stateData, nil
+	}
+
+	if filter.Results != "" {
+		filtered, err := utils.EvaluateExpression(filter.Results, deepCopy(result))
+		if err != nil {
+			e.logger.ErrorContextf(ctx, "Failed to apply results filter: %v", err)
+			return nil, NewWorkflowError(ErrDataTransform, "Failed to apply action results filter").
+				WithActivity(actionName(action)).
+				WithContext("results", filter.Results).
+				WithCause(err)
+		}
+		result = filtered
+	}
+
+	if filter.ToStateData == "" {
+		return result, nil
+	}
+
+	// Merge into whatever is already stored at the target path
+	existing, err := utils.EvaluateExpression(filter.ToStateData, deepCopy(stateData))
+	if err == nil {
+		result = mergeData(existing, result)
+	}
+
+	merged, err := utils.SetExpressionPath(stateData, filter.ToStateData, result)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to apply toStateData filter: %v", err)
+		return nil, NewWorkflowError(ErrDataTransform, "Failed to apply action toStateData filter").
+			WithActivity(actionName(action)).
+			WithContext("toStateData", filter.ToStateData).
+			WithCause(err)
+	}
+	return merged, nil
+}
+
+// applyEventDataFilter returns the state data after an event was consumed. The data expression is evaluated
+// against the event payload, and toStateData merges the (filtered) payload into that path of the state data.
+// The parser defaults useData to true for states without an eventDataFilter, so the payload is merged into the
+// state data. With useData set to false the state data is left untouched.
+func (e *Engine) applyEventDataFilter(ctx context.Context, filter sw.EventDataFilter, stateData interface{}, event consumedEvent) (interface{}, error) {
+	if !filter.UseData {
+		e.logger.DebugContext(ctx, "Discarding event data, useData is false")
+		return stateData, nil
+	}
+
+	payload := deepCopy(event.payload())
+	if filter.Data != "" {
+		filtered, err := utils.EvaluateExpression(filter.Data, payload)
+		if err != nil {
+			e.logger.ErrorContextf(ctx, "Failed to apply event data filter: %v", err)
+			return nil, NewWorkflowError(ErrDataTransform, "Failed to apply event data filter").
+				WithContext("eventRef", event.ref).
+				WithContext("data", filter.Data).
+				WithCause(err)
+		}
+		payload = filtered
+	}
+
+	if payload == nil {
+		// Events without a payload only signal that something happened
+		return stateData, nil
+	}
+
+	if filter.ToStateData == "" {
+		return mergeData(stateData, payload), nil
+	}
+
+	// Merge into whatever is already stored at the target path
+	existing, err := utils.EvaluateExpression(filter.ToStateData, deepCopy(stateData))
+	if err == nil {
+		payload = mergeData(existing, payload)
+	}
+
+	merged, err := utils.SetExpressionPath(stateData, filter.ToStateData, payload)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to apply event toStateData filter: %v", err)
+		return nil, NewWorkflowError(ErrDataTransform, "Failed to apply event toStateData filter").
+			WithContext("eventRef", event.ref).
+			WithContext("toStateData", filter.ToStateData).
+			WithCause(err)
+	}
+	return merged, nil
+}
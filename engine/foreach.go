@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultIterationParam is the name the current element is exposed under when a foreach state has no iterationParam
+const defaultIterationParam = "item"
+
+// executeForEachState runs the state's actions once for every element of the input collection.
+// In parallel mode at most batchSize iterations run at a time, in sequential mode they run one after another.
+// The outputs of all iterations are written, in collection order, to the outputCollection of the state data.
+func (e *Engine) executeForEachState(ctx context.Context, state *sw.ForEachState, data *WorkflowData, stateExec *StateExecution) (*StateResult, error) {
+	collection, err := e.evaluateInputCollection(ctx, state, data)
+	if err != nil {
+		return nil, err
+	}
+
+	iterationParam := state.IterationParam
+	if iterationParam == "" {
+		iterationParam = defaultIterationParam
+	}
+
+	concurrency := 1
+	if state.Mode != sw.ForEachModeTypeSequential && len(collection) > 0 {
+		concurrency = len(collection)
+		if state.BatchSize != nil {
+			if batchSize := state.BatchSize.IntValue(); batchSize > 0 && batchSize < concurrency {
+				concurrency = batchSize
+			}
+		}
+	}
+
+	e.logger.DebugContextf(ctx, "Iterating over %d elements as '%s' with concurrency %d", len(collection), iterationParam, concurrency)
+
+	outputs, err := e.executeIterations(ctx, state, collection, iterationParam, concurrency, data, stateExec)
+	if err != nil {
+		return nil, err
+	}
+
+	output := data.Current
+	if state.OutputCollection != "" {
+		output, err = utils.SetExpressionPath(data.Current, state.OutputCollection, outputs)
+		if err != nil {
+			e.logger.ErrorContextf(ctx, "Failed to set output collection: %v", err)
+			return nil, NewWorkflowError(ErrDataTransform, "Failed to set output collection").
+				WithState(state.GetName()).
+				WithContext("outputCollection", state.OutputCollection).
+				WithCause(err)
+		}
+	}
+
+	var nextState string
+	if state.GetTransition() != nil {
+		nextState = state.GetTransition().NextState
+	}
+
+	return &StateResult{
+		Data:      output,
+		NextState: nextState,
+	}, nil
+}
+
+// evaluateInputCollection evaluates the inputCollection expression against the workflow data
+func (e *Engine) evaluateInputCollection(ctx context.Context, state *sw.ForEachState, data *WorkflowData) ([]interface{}, error) {
+	value, err := utils.EvaluateExpression(state.InputCollection, data.ToMap())
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to evaluate input collection: %v", err)
+		return nil, NewWorkflowError(ErrExpressionEval, "Failed to evaluate input collection").
+			WithState(state.GetName()).
+			WithContext("inputCollection", state.InputCollection).
+			WithCause(err)
+	}
+
+	if value == nil {
+		return []interface{}{}, nil
+	}
+
+	collection, ok := value.([]interface{})
+	if !ok {
+		e.logger.ErrorContextf(ctx, "Input collection did not evaluate to an array, got %T", value)
+		return nil, NewWorkflowError(ErrDataTypeConversion, fmt.Sprintf("input collection must be an array, got %T", value)).
+			WithState(state.GetName()).
+			WithContext("inputCollection", state.InputCollection)
+	}
+
+	return collection, nil
+}
+
+// executeIterations runs the iterations with the given concurrency. The first failing iteration cancels the others.
+func (e *Engine) executeIterations(ctx context.Context, state *sw.ForEachState, collection []interface{}, iterationParam string, concurrency int, data *WorkflowData, stateExec *StateExecution) ([]interface{}, error) {
+	iterCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	outputs := make([]interface{}, len(collection))
+	iterExecs := make([]*StateExecution, len(collection))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	slots := make(chan struct{}, concurrency)
+
+	for i, item := range collection {
+		slots <- struct{}{}
+		if iterCtx.Err() != nil {
+			<-slots
+			break
+		}
+
+		if stateExec != nil {
+			iterExecs[i] = &StateExecution{
+				Name:      fmt.Sprintf("%s[%d]", state.GetName(), i),
+				Type:      "iteration",
+				StartTime: time.Now(),
+				Input:     item,
+			}
+		}
+
+		wg.Add(1)
+		go func(index int, iterData *WorkflowData, iterExec *StateExecution) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			output, err := e.executeIteration(iterCtx, state.GetName(), index, state.Actions, iterData, iterExec)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("iteration %d failed: %w", index, err)
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			outputs[index] = output
+		}(i, data.withLocal(iterationParam, item), iterExecs[i])
+	}
+	wg.Wait()
+
+	if stateExec != nil {
+		for _, iterExec := range iterExecs {
+			if iterExec != nil {
+				stateExec.Iterations = append(stateExec.Iterations, *iterExec)
+			}
+		}
+	}
+
+	if firstErr != nil {
+		e.logger.ErrorContextf(ctx, "Foreach state failed: %v", firstErr)
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
+
+// executeIteration executes the actions of a single foreach iteration
+func (e *Engine) executeIteration(ctx context.Context, stateName string, index int, actions []sw.Action, data *WorkflowData, iterExec *StateExecution) (output interface{}, err error) {
+	if e.telemetry != nil {
+		var iterationSpan trace.Span
+		ctx, iterationSpan = e.telemetry.StartIterationSpan(ctx, stateName, index)
+		defer func() {
+			if err != nil {
+				iterationSpan.RecordError(err)
+				iterationSpan.SetStatus(codes.Error, err.Error())
+			} else {
+				iterationSpan.SetStatus(codes.Ok, "")
+			}
+			iterationSpan.End()
+		}()
+	}
+
+	if iterExec != nil {
+		defer func() {
+			iterExec.EndTime = time.Now()
+			if err != nil {
+				iterExec.Error = err.Error()
+			} else {
+				iterExec.Output = output
+			}
+		}()
+	}
+
+	e.logger.DebugContextf(ctx, "Executing iteration %d", index)
+
+	result, err := e.executeActions(ctx, actions, data, iterExec)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Data, nil
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+)
+
+// resolveFunction resolves the function an action references through the functions of the running workflow. Functions
+// are resolved when the run first calls them, within its context, and cached for the rest of the run.
+func (e *Engine) resolveFunction(ctx context.Context, name string) (*parser.Function, error) {
+	exec := executionFromContext(ctx)
+	if exec == nil {
+		return nil, NewWorkflowError(ErrFunctionNotFound, "Function not found outside of a workflow execution").
+			WithActivity(name)
+	}
+
+	exec.mu.Lock()
+	function, ok := exec.functions[name]
+	exec.mu.Unlock()
+	if ok {
+		return function, nil
+	}
+
+	// Resolving can fetch an OpenAPI document, so it runs without holding the lock
+	function, err := parser.ResolveFunction(ctx, e.registry, &exec.workflow.Workflow, name)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to resolve function '%s': %v", name, err)
+		return nil, NewWorkflowError(ErrFunctionNotFound, "Failed to resolve function").
+			WithActivity(name).
+			WithWorkflow(exec.workflow.ID).
+			WithCause(err)
+	}
+
+	exec.mu.Lock()
+	exec.functions[name] = function
+	exec.mu.Unlock()
+	return function, nil
+}
+
+// functionArguments converts the evaluated arguments of an action into the arguments of the activity that runs its
+// function. Custom and expression functions take them unchanged.
+func functionArguments(function *parser.Function, arguments map[string]any) (map[string]any, error) {
+	if parser.FunctionType(function.Function) == sw.FunctionTypeREST {
+		return restArguments(function.OpenAPI, arguments)
+	}
+	return arguments, nil
+}
+
+// evaluateExpressionFunction evaluates the expression of an expression function against the action's input, with
+// the arguments of the action bound to $args
+func (e *Engine) evaluateExpressionFunction(ctx context.Context, function *parser.Function, arguments map[string]any, data *WorkflowData) (interface{}, error) {
+	e.logger.DebugContextf(ctx, "Evaluating expression function '%s': %s", function.Name, function.Operation)
+	result, err := utils.EvaluateExpressionWithArgs(function.Operation, data.Current, arguments)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to evaluate expression function '%s': %v", function.Name, err)
+		return nil, NewWorkflowError(ErrExpressionEval, "Failed to evaluate expression function").
+			WithActivity(function.Name).
+			WithExpression(function.Operation).
+			WithCause(err)
+	}
+	return result, nil
+}
+
+// expandFunctions rewrites the references to the running workflow's expression functions in an expression, e.g.
+// "${ fn:isAdult }", into a bare JQ query that defines them
+func expandFunctions(ctx context.Context, expr string) (string, error) {
+	var functions map[string]string
+	if workflow := workflowFromContext(ctx); workflow != nil {
+		functions = parser.ExpressionFunctions(&workflow.Workflow)
+	}
+	return utils.ExpandFunctionReferences(expr, functions)
+}
+
+// evaluateFunctionExpression evaluates an expression that can reference expression functions against the state data
+func (e *Engine) evaluateFunctionExpression(ctx context.Context, expr string, data *WorkflowData) (interface{}, error) {
+	query, err := expandFunctions(ctx, expr)
+	if err != nil {
+		return nil, err
+	}
+	return utils.EvaluateExpression(query, data.ToMap())
+}
+
+// restArguments converts the arguments of a rest function into the arguments of the HTTPRequest activity. Arguments
+// named after a parameter of the operation fill that parameter, unless they are null; the others form the JSON
+// request body.
+func restArguments(operation *parser.OpenAPIOperation, arguments map[string]any) (map[string]any, error) {
+	remaining := make(map[string]any, len(arguments))
+	for name, value := range arguments {
+		remaining[name] = value
+	}
+
+	target := operation.URL
+	query := url.Values{}
+	headers := map[string]string{}
+	for _, parameter := range operation.Parameters {
+		value, ok := remaining[parameter.Name]
+		if !ok || value == nil {
+			if parameter.Required {
+				return nil, fmt.Errorf("missing required %s parameter '%s' of operation '%s'", parameter.In, parameter.Name, operation.ID)
+			}
+			continue
+		}
+		delete(remaining, parameter.Name)
+
+		formatted := formatParameter(value)
+		switch parameter.In {
+		case "path":
+			target = strings.ReplaceAll(target, "{"+parameter.Name+"}", url.PathEscape(formatted))
+		case "query":
+			query.Add(parameter.Name, formatted)
+		case "header":
+			headers[parameter.Name] = formatted
+		default:
+			return nil, fmt.Errorf("%s parameter '%s' of operation '%s' is not supported", parameter.In, parameter.Name, operation.ID)
+		}
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	request := map[string]any{
+		"method":      operation.Method,
+		"url":         target,
+		"headers":     headers,
+		"failOnError": true,
+	}
+	if len(remaining) > 0 {
+		if !operation.HasBody {
+			for name := range remaining {
+				return nil, fmt.Errorf("operation '%s' has no parameter '%s' and takes no request body", operation.ID, name)
+			}
+		}
+		request["body"] = remaining
+	}
+	return request, nil
+}
+
+// formatParameter formats the value of a path, query or header parameter. Numbers decoded from JSON are float64, and
+// are formatted without an exponent, e.g. 1234567 rather than 1.234567e+06.
+func formatParameter(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+)
+
+// executeInjectState merges the state's static data into the current data. JQ templates inside the data are
+// resolved against the workflow data first, so "${ .globals.region }" injects the value of the global.
+func (e *Engine) executeInjectState(ctx context.Context, state *sw.InjectState, data *WorkflowData, stateExec *StateExecution) (*StateResult, error) {
+	injected, err := utils.EvaluateArgumentsRecursively(convertToAnyMap(state.Data), data.ToMap())
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to evaluate inject data: %v", err)
+		return nil, NewWorkflowError(ErrExpressionEval, "Failed to evaluate inject data").
+			WithState(state.GetName()).
+			WithCause(err)
+	}
+
+	e.logger.DebugContextf(ctx, "Injecting data: %+v", injected)
+
+	var nextState string
+	if state.GetTransition() != nil {
+		nextState = state.GetTransition().NextState
+	}
+
+	return &StateResult{
+		Data:      mergeData(data.Current, injected),
+		NextState: nextState,
+	}, nil
+}
@@ -4,15 +4,16 @@ import (
 	"context"
 	"strings"
 
-	"github.com/kshitiz1403/jsonjuggler/activities"
 	"github.com/kshitiz1403/jsonjuggler/logger"
 	sw "github.com/serverlessworkflow/sdk-go/v2/model"
 	"github.com/spf13/cast"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// handleStateError processes the error against state's onErrors configuration
-func (e *Engine) handleStateError(ctx context.Context, err *activities.ActivityError, onErrors []sw.OnError) (string, bool) {
+// handleStateError processes the error against state's onErrors configuration. Error references are matched
+// against the workflow's error definitions, see matchesErrorRef. With substring error matching enabled,
+// a reference contained in the error message matches as well.
+func (e *Engine) handleStateError(ctx context.Context, code string, err error, onErrors []sw.OnError) (sw.OnError, bool) {
 	errMsg := err.Error()
 
 	// Add error handling span
@@ -23,26 +24,49 @@ func (e *Engine) handleStateError(ctx context.Context, err *activities.ActivityE
 		if stateNameValue := ctx.Value(logger.StateNameKey); stateNameValue != nil {
 			stateName = cast.ToString(stateNameValue)
 		}
-		ctx, errorHandlingSpan = e.telemetry.StartErrorHandlingSpan(ctx, stateName, string(err.Code), errMsg, "evaluate_handlers")
+		ctx, errorHandlingSpan = e.telemetry.StartErrorHandlingSpan(ctx, stateName, code, errMsg, "evaluate_handlers")
 		defer errorHandlingSpan.End()
 	}
 
 	// First try to match specific error references
+	workflow := workflowFromContext(ctx)
 	for _, handler := range onErrors {
-		if handler.ErrorRef != "DefaultErrorRef" && strings.Contains(errMsg, handler.ErrorRef) {
-			e.logger.DebugContextf(ctx, "Matched error handler for ref '%s', transitioning to state '%s'", handler.ErrorRef, handler.Transition.NextState)
-			return handler.Transition.NextState, true
+		for _, ref := range errorRefs(handler) {
+			if ref == "DefaultErrorRef" {
+				continue
+			}
+			if matchesErrorRef(workflow, err, ref) || (e.substringErrorMatching && strings.Contains(errMsg, ref)) {
+				e.logger.DebugContextf(ctx, "Matched error handler for ref '%s', transitioning to state '%s'", ref, handlerNextState(handler))
+				return handler, true
+			}
 		}
 	}
 
 	// Then look for DefaultErrorRef if none of the specific ones matched
 	for _, handler := range onErrors {
 		if handler.ErrorRef == "DefaultErrorRef" {
-			e.logger.DebugContextf(ctx, "Using default error handler, transitioning to state '%s'", handler.Transition.NextState)
-			return handler.Transition.NextState, true
+			e.logger.DebugContextf(ctx, "Using default error handler, transitioning to state '%s'", handlerNextState(handler))
+			return handler, true
 		}
 	}
 
 	e.logger.DebugContext(ctx, "No error handler matched")
-	return "", false
+	return sw.OnError{}, false
+}
+
+// errorRefs returns the error references of an onErrors definition, which uses either errorRef or errorRefs
+func errorRefs(handler sw.OnError) []string {
+	if handler.ErrorRef != "" {
+		return []string{handler.ErrorRef}
+	}
+	return handler.ErrorRefs
+}
+
+// handlerNextState returns the state an onErrors definition transitions to, or an empty name if it ends
+// the workflow
+func handlerNextState(handler sw.OnError) string {
+	if handler.Transition == nil {
+		return ""
+	}
+	return handler.Transition.NextState
 }
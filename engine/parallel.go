@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// branchOutcome carries the result of a single parallel branch back to the parallel state
+type branchOutcome struct {
+	index int
+	data  interface{}
+	err   error
+}
+
+// executeParallelState runs all branches of a parallel state concurrently and merges their outputs into the state data.
+// With completionType allOf every branch must complete, with atLeast the state completes as soon as numCompleted
+// branches have completed. Once the outcome is decided the remaining branches are cancelled.
+func (e *Engine) executeParallelState(ctx context.Context, state *sw.ParallelState, data *WorkflowData, stateExec *StateExecution) (*StateResult, error) {
+	required, err := requiredBranches(state)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Invalid parallel state completion: %v", err)
+		return nil, err
+	}
+
+	e.logger.DebugContextf(ctx, "Running %d branches, %d required to complete", len(state.Branches), required)
+
+	branchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	branchExecs := make([]*StateExecution, len(state.Branches))
+	outcomes := make(chan branchOutcome, len(state.Branches))
+	for i, branch := range state.Branches {
+		if stateExec != nil {
+			branchExecs[i] = &StateExecution{
+				Name:      branch.Name,
+				Type:      "branch",
+				StartTime: time.Now(),
+				Input:     data.Current,
+			}
+		}
+
+		go func(index int, branch sw.Branch, branchExec *StateExecution) {
+			output, err := e.executeBranch(branchCtx, state.GetName(), branch, data.clone(), branchExec)
+			outcomes <- branchOutcome{index: index, data: output, err: err}
+		}(i, branch, branchExecs[i])
+	}
+
+	// Wait for every branch to return so that no branch outlives the state, deciding the
+	// outcome as soon as enough branches have either completed or failed.
+	outputs := make([]interface{}, len(state.Branches))
+	succeeded := make([]bool, len(state.Branches))
+	completed, failed := 0, 0
+	decided := false
+	var branchErr error
+	for range state.Branches {
+		outcome := <-outcomes
+		if outcome.err != nil {
+			if decided {
+				continue
+			}
+			failed++
+			if failed > len(state.Branches)-required {
+				decided = true
+				branchErr = fmt.Errorf("branch '%s' failed: %w", state.Branches[outcome.index].Name, outcome.err)
+				e.logger.ErrorContextf(ctx, "Parallel state failed, cancelling remaining branches: %v", outcome.err)
+				cancel()
+			}
+			continue
+		}
+
+		outputs[outcome.index] = outcome.data
+		succeeded[outcome.index] = true
+		completed++
+		if !decided && completed >= required {
+			decided = true
+			e.logger.DebugContextf(ctx, "%d branches completed, cancelling remaining branches", completed)
+			cancel()
+		}
+	}
+
+	if stateExec != nil {
+		for _, branchExec := range branchExecs {
+			stateExec.Branches = append(stateExec.Branches, *branchExec)
+		}
+	}
+
+	if branchErr != nil {
+		return nil, branchErr
+	}
+
+	// Merge outputs in branch definition order so the result does not depend on completion order
+	merged := data.Current
+	for i, output := range outputs {
+		if succeeded[i] {
+			merged = mergeData(merged, output)
+		}
+	}
+
+	var nextState string
+	if state.GetTransition() != nil {
+		nextState = state.GetTransition().NextState
+	}
+
+	return &StateResult{
+		Data:      merged,
+		NextState: nextState,
+	}, nil
+}
+
+// executeBranch executes the actions of a single parallel branch against its own copy of the workflow data
+func (e *Engine) executeBranch(ctx context.Context, stateName string, branch sw.Branch, data *WorkflowData, branchExec *StateExecution) (output interface{}, err error) {
+	if e.telemetry != nil {
+		var branchSpan trace.Span
+		ctx, branchSpan = e.telemetry.StartBranchSpan(ctx, stateName, branch.Name)
+		defer func() {
+			if err != nil {
+				branchSpan.RecordError(err)
+				branchSpan.SetStatus(codes.Error, err.Error())
+			} else {
+				branchSpan.SetStatus(codes.Ok, "")
+			}
+			branchSpan.End()
+		}()
+	}
+
+	if branchExec != nil {
+		defer func() {
+			branchExec.EndTime = time.Now()
+			if err != nil {
+				branchExec.Error = err.Error()
+			} else {
+				branchExec.Output = output
+			}
+		}()
+	}
+
+	e.logger.DebugContextf(ctx, "Executing branch '%s'", branch.Name)
+
+	result, err := e.executeActions(ctx, branch.Actions, data, branchExec)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Data, nil
+}
+
+// requiredBranches returns the number of branches that must complete for the parallel state to complete
+func requiredBranches(state *sw.ParallelState) (int, error) {
+	if state.CompletionType != sw.CompletionTypeAtLeast {
+		return len(state.Branches), nil
+	}
+
+	numCompleted := state.NumCompleted.IntValue()
+	if numCompleted <= 0 || numCompleted > len(state.Branches) {
+		return 0, NewWorkflowError(ErrStateInvalid, fmt.Sprintf("numCompleted must be between 1 and %d", len(state.Branches))).
+			WithState(state.GetName()).
+			WithContext("numCompleted", state.NumCompleted.String())
+	}
+	return numCompleted, nil
+}
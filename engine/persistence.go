@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/persistence"
+)
+
+// Resume continues a checkpointed run. A run that was interrupted, e.g. because the process stopped
+// while a state was running, continues with that state. A failed run retries the state it failed in.
+// The workflow definition is taken from the checkpoint, and the workflowExecTimeout starts over.
+// Runs that are still executing in this engine cannot be resumed. The store is not locked, so engines that
+// share a store must make sure that only one of them resumes a run.
+func (e *Engine) Resume(ctx context.Context, runID string) (*ExecutionResult, error) {
+	if e.store == nil {
+		return nil, NewWorkflowError(ErrPersistence, "no persistence store configured").
+			WithContext("runId", runID)
+	}
+
+	if !e.active.start(runID) {
+		return nil, NewWorkflowError(ErrWorkflowInvalid, fmt.Sprintf("run %s is still running", runID)).
+			WithContext("runId", runID)
+	}
+	defer e.active.finish(runID)
+
+	checkpoint, err := e.store.Load(ctx, runID)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to load checkpoint of run %s: %v", runID, err)
+		code := ErrPersistence
+		if errors.Is(err, persistence.ErrNotFound) {
+			code = ErrRunNotFound
+		}
+		return nil, NewWorkflowError(code, "failed to load checkpoint").
+			WithContext("runId", runID).
+			WithCause(err)
+	}
+
+	if checkpoint.Status == persistence.StatusCompleted {
+		return nil, NewWorkflowError(ErrWorkflowInvalid, fmt.Sprintf("run %s has already completed", runID)).
+			WithWorkflow(checkpoint.WorkflowID).
+			WithContext("runId", runID)
+	}
+
+	// The definition is parsed like the original one, rather than decoded directly, so that it is validated again
+	workflow, err := parser.NewParser(e.registry).ParseFromJSON(checkpoint.Definition)
+	if err != nil {
+		return nil, NewWorkflowError(ErrWorkflowInvalid, "failed to restore workflow definition").
+			WithWorkflow(checkpoint.WorkflowID).
+			WithContext("runId", runID).
+			WithCause(err)
+	}
+	workflow.Dir = checkpoint.DefinitionDir
+
+	workflowData := &WorkflowData{
+		Initial: checkpoint.Initial,
+		Current: checkpoint.Current,
+		States:  checkpoint.States,
+		Globals: checkpoint.Globals,
+		Error:   checkpoint.CaughtError,
+	}
+	if workflowData.States == nil {
+		workflowData.States = make(map[string]interface{})
+	}
+	if workflowData.Globals == nil {
+		workflowData.Globals = make(map[string]interface{})
+	}
+
+	e.logger.InfoContextf(ctx, "Resuming run %s of workflow %s at state %s", runID, checkpoint.WorkflowID, checkpoint.NextState)
+	exec := newExecution(runID, workflow, e.debugEnabled)
+	exec.compensable = checkpoint.Compensable
+	if checkpoint.Callback != nil && checkpoint.Callback.State == checkpoint.NextState {
+		exec.callback = checkpoint.Callback
+	}
+	return e.run(ctx, exec, workflowData, checkpoint.NextState)
+}
+
+// checkpoint saves the progress of the run to the engine's store, if there is one. nextState is the state
+// the run continues with, an empty nextState marks the run as completed and a non-nil runErr as failed.
+func (e *Engine) checkpoint(ctx context.Context, exec *execution, data *WorkflowData, nextState string, runErr error) error {
+	if e.store == nil {
+		return nil
+	}
+
+	definition, err := exec.definition()
+	if err != nil {
+		return NewWorkflowError(ErrPersistence, "failed to serialize workflow definition").
+			WithWorkflow(exec.workflow.ID).
+			WithContext("runId", exec.runID).
+			WithCause(err)
+	}
+
+	checkpoint := &persistence.Checkpoint{
+		RunID:           exec.runID,
+		WorkflowID:      exec.workflow.ID,
+		WorkflowVersion: exec.workflow.Version,
+		Definition:      definition,
+		DefinitionDir:   exec.workflow.Dir,
+		Status:          persistence.StatusRunning,
+		NextState:       nextState,
+		Initial:         data.Initial,
+		Current:         data.Current,
+		States:          data.States,
+		Globals:         data.Globals,
+		CaughtError:     data.Error,
+		Compensable:     exec.compensable,
+		UpdatedAt:       time.Now(),
+	}
+	if nextState == "" {
+		checkpoint.Status = persistence.StatusCompleted
+	}
+	if runErr != nil {
+		checkpoint.Status = persistence.StatusFailed
+		checkpoint.Error = runErr.Error()
+	}
+
+	// Checkpoints are also written while a cancelled run is winding down
+	if err := e.store.Save(context.WithoutCancel(ctx), checkpoint); err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to save checkpoint: %v", err)
+		return NewWorkflowError(ErrPersistence, "failed to save checkpoint").
+			WithWorkflow(exec.workflow.ID).
+			WithState(nextState).
+			WithContext("runId", exec.runID).
+			WithCause(err)
+	}
+
+	e.logger.DebugContextf(ctx, "Saved checkpoint with status %s, next state '%s'", checkpoint.Status, nextState)
+	return nil
+}
+
+// saveCallback records in the run's checkpoint that the run waits in the callback state, with the state data
+// after the state's action, so that a resumed run waits for the callback again without performing the action
+// twice. Only callback states the run transitioned to are recorded, not those of parallel branches or loops.
+func (e *Engine) saveCallback(ctx context.Context, exec *execution, stateName string, data interface{}) error {
+	if e.store == nil || exec == nil {
+		return nil
+	}
+
+	// The action was performed, so the callback is recorded even if the run is being cancelled
+	ctx = context.WithoutCancel(ctx)
+	checkpoint, err := e.store.Load(ctx, exec.runID)
+	if err == nil && checkpoint.NextState == stateName {
+		checkpoint.Callback = &persistence.Callback{State: stateName, Data: data}
+		checkpoint.UpdatedAt = time.Now()
+		err = e.store.Save(ctx, checkpoint)
+	}
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to save checkpoint: %v", err)
+		return NewWorkflowError(ErrPersistence, "failed to save checkpoint").
+			WithWorkflow(exec.workflow.ID).
+			WithState(stateName).
+			WithContext("runId", exec.runID).
+			WithCause(err)
+	}
+	return nil
+}
+
+// failRun records a failed run and returns its error. A run that failed because its context was
+// cancelled keeps its last checkpoint, so that it can be resumed from the state that was interrupted.
+func (e *Engine) failRun(ctx context.Context, exec *execution, data *WorkflowData, stateName string, runErr error) error {
+	if ctx.Err() != nil {
+		return runErr
+	}
+	// The run already failed, a checkpoint that cannot be saved is only logged
+	_ = e.checkpoint(ctx, exec, data, stateName, runErr)
+	return runErr
+}
@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+	"github.com/serverlessworkflow/sdk-go/v2/util/floatstr"
+)
+
+// defaultRetryPolicy is applied to actions without a retryRef when the workflow enables autoRetries
+var defaultRetryPolicy = &retryPolicy{
+	name:        "default",
+	maxAttempts: 3,
+	delay:       time.Second,
+	maxDelay:    30 * time.Second,
+	multiplier:  2,
+}
+
+// retryPolicy is a parsed workflow retry definition
+type retryPolicy struct {
+	name string
+	// maxAttempts is the total number of attempts, including the first one
+	maxAttempts int
+	delay       time.Duration
+	maxDelay    time.Duration
+	increment   time.Duration
+	multiplier  float64
+	// jitterFraction is the maximum random variation relative to the delay (between 0 and 1)
+	jitterFraction float64
+	// jitterDuration is the maximum absolute random variation of the delay
+	jitterDuration time.Duration
+}
+
+// newRetryPolicy parses the durations and numbers of a workflow retry definition
+func newRetryPolicy(def sw.Retry) (*retryPolicy, error) {
+	policy := &retryPolicy{
+		name:        def.Name,
+		maxAttempts: def.MaxAttempts.IntValue(),
+		multiplier:  1,
+	}
+
+	durations := []struct {
+		field string
+		value string
+		dest  *time.Duration
+	}{
+		{"delay", def.Delay, &policy.delay},
+		{"maxDelay", def.MaxDelay, &policy.maxDelay},
+		{"increment", def.Increment, &policy.increment},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := utils.ParseISODuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s '%s' in retry '%s': %w", d.field, d.value, def.Name, err)
+		}
+		*d.dest = parsed
+	}
+
+	if def.Multiplier != nil {
+		if multiplier := float64(def.Multiplier.FloatValue()); multiplier > 0 {
+			policy.multiplier = multiplier
+		}
+	}
+
+	if def.Jitter.Type == floatstr.String && def.Jitter.StrVal != "" {
+		jitter, err := utils.ParseISODuration(def.Jitter.StrVal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jitter '%s' in retry '%s': %w", def.Jitter.StrVal, def.Name, err)
+		}
+		policy.jitterDuration = jitter
+	} else {
+		policy.jitterFraction = math.Min(math.Max(float64(def.Jitter.FloatValue()), 0), 1)
+	}
+
+	return policy, nil
+}
+
+// backoff returns the delay to wait after the given (1-based) failed attempt
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.delay)*math.Pow(p.multiplier, float64(attempt-1)) + float64(p.increment)*float64(attempt-1)
+
+	jitter := p.jitterFraction * delay
+	if p.jitterDuration > 0 {
+		jitter = float64(p.jitterDuration)
+	}
+	if jitter > 0 {
+		delay += (rand.Float64()*2 - 1) * jitter
+	}
+
+	if p.maxDelay > 0 && delay > float64(p.maxDelay) {
+		delay = float64(p.maxDelay)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
+
+// resolveRetryPolicy returns the retry policy that applies to an action, or nil if the action must not be retried
+func (e *Engine) resolveRetryPolicy(ctx context.Context, action sw.Action) (*retryPolicy, error) {
+	workflow := workflowFromContext(ctx)
+	if action.RetryRef == "" {
+		if workflow != nil && workflow.AutoRetries {
+			return defaultRetryPolicy, nil
+		}
+		return nil, nil
+	}
+
+	if workflow != nil {
+		for _, def := range workflow.Retries {
+			if def.Name == action.RetryRef {
+				policy, err := newRetryPolicy(def)
+				if err != nil {
+					return nil, NewWorkflowError(ErrRetryNotFound, "Invalid retry definition").
+						WithActivity(actionName(action)).
+						WithContext("retryRef", action.RetryRef).
+						WithCause(err)
+				}
+				return policy, nil
+			}
+		}
+	}
+
+	return nil, NewWorkflowError(ErrRetryNotFound, fmt.Sprintf("retry definition '%s' not found", action.RetryRef)).
+		WithActivity(actionName(action)).
+		WithContext("retryRef", action.RetryRef)
+}
+
+// isRetryable decides whether a failed action may be retried. With autoRetries every error is retried
+// except the action's nonRetryableErrors, otherwise only the action's retryableErrors are retried
+// (or every error when the action does not list any).
+func (e *Engine) isRetryable(ctx context.Context, action sw.Action, err error) bool {
+	workflow := workflowFromContext(ctx)
+	if workflow != nil && workflow.AutoRetries {
+		for _, ref := range action.NonRetryableErrors {
+			if matchesErrorRef(workflow, err, ref) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if len(action.RetryableErrors) == 0 {
+		return true
+	}
+	for _, ref := range action.RetryableErrors {
+		if matchesErrorRef(workflow, err, ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// executeActivityWithRetry executes an activity, retrying failed attempts with backoff according to the
+// action's retry policy. Waiting between attempts stops as soon as the context is done.
+func (e *Engine) executeActivityWithRetry(ctx context.Context, action sw.Action, activity activities.Activity, arguments map[string]any, actionResult *ActionResult) (interface{}, error) {
+	activityName := action.FunctionRef.RefName
+
+	policy, err := e.resolveRetryPolicy(ctx, action)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "Failed to resolve retry policy: %v", err)
+		return nil, err
+	}
+	if policy == nil {
+		return e.executeActivityWithTelemetry(ctx, activityName, activity, arguments)
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptResult := ActionAttempt{Attempt: attempt, StartTime: time.Now()}
+		result, err := e.executeActivityWithTelemetry(ctx, activityName, activity, arguments)
+		attemptResult.EndTime = time.Now()
+
+		if err == nil {
+			if actionResult != nil {
+				actionResult.Attempts = append(actionResult.Attempts, attemptResult)
+			}
+			return result, nil
+		}
+		attemptResult.Error = err.Error()
+
+		if attempt >= policy.maxAttempts || ctx.Err() != nil || !e.isRetryable(ctx, action, err) {
+			if actionResult != nil {
+				actionResult.Attempts = append(actionResult.Attempts, attemptResult)
+			}
+			if attempt > 1 {
+				e.logger.ErrorContextf(ctx, "Activity failed after %d attempts with retry policy '%s'", attempt, policy.name)
+			}
+			return nil, err
+		}
+
+		delay := policy.backoff(attempt)
+		attemptResult.Delay = delay.String()
+		if actionResult != nil {
+			actionResult.Attempts = append(actionResult.Attempts, attemptResult)
+		}
+
+		e.logger.WarnContextf(ctx, "Activity attempt %d/%d failed, retrying in %v: %v", attempt, policy.maxAttempts, delay, err)
+		if e.telemetry != nil {
+			e.telemetry.RecordActivityRetry(ctx, activityName, attempt, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, activities.NewActivityError(
+				activities.ErrExecutionFailed,
+				"Activity retry cancelled",
+				activityName,
+			).WithCause(ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+)
+
+// outputSchemaKey is the state metadata key of the JSON Schema the state's output is validated against
+const outputSchemaKey = "outputSchema"
+
+// schemas compiles JSON Schemas on first use and keeps them for later executions, until the watched workflows are
+// reloaded. Schemas are referenced by file path or URL, see schemaLocation for how relative paths are resolved.
+type schemas struct {
+	mu       sync.Mutex
+	compiled map[string]*jsonschema.Schema
+	// generation counts the resets, so that a schema compiled before a reset is not kept after it
+	generation int
+}
+
+func newSchemas() *schemas {
+	return &schemas{compiled: make(map[string]*jsonschema.Schema)}
+}
+
+// get returns the compiled schema at the location. Compiling can fetch the schema and the schemas it references,
+// so it runs without holding the lock.
+func (s *schemas) get(location string) (*jsonschema.Schema, error) {
+	s.mu.Lock()
+	schema, ok := s.compiled[location]
+	generation := s.generation
+	s.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := jsonschema.Compile(location)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation == generation {
+		s.compiled[location] = schema
+	}
+	return schema, nil
+}
+
+// reset drops the compiled schemas, so that they are compiled again from their current files
+func (s *schemas) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compiled = make(map[string]*jsonschema.Schema)
+	s.generation++
+}
+
+// validateData validates the data against the schema at the location. Data that does not match the schema fails
+// with an ErrDataValidation error listing the violations in its "violations" context.
+func (e *Engine) validateData(location string, data interface{}, subject string) *WorkflowError {
+	schema, err := e.schemas.get(location)
+	if err != nil {
+		return NewWorkflowError(ErrWorkflowInvalid, fmt.Sprintf("failed to load %s schema", subject)).
+			WithContext("schema", location).
+			WithCause(err)
+	}
+
+	normalized, err := utils.NormalizeJSON(data)
+	if err != nil {
+		return NewWorkflowError(ErrDataValidation, fmt.Sprintf("%s is not valid JSON", subject)).
+			WithContext("schema", location).
+			WithCause(err)
+	}
+
+	if err := schema.Validate(normalized); err != nil {
+		var validationErr *jsonschema.ValidationError
+		if !errors.As(err, &validationErr) {
+			return NewWorkflowError(ErrDataValidation, fmt.Sprintf("failed to validate %s", subject)).
+				WithContext("schema", location).
+				WithCause(err)
+		}
+		violations := schemaViolations(validationErr)
+		return NewWorkflowError(ErrDataValidation, fmt.Sprintf("%s does not match schema: %s", subject, strings.Join(violations, "; "))).
+			WithContext("schema", location).
+			WithContext("violations", violations)
+	}
+	return nil
+}
+
+// schemaViolations flattens a schema validation error into its violations, e.g. "/amount: expected number, but got string"
+func schemaViolations(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) == 0 {
+		location := err.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return []string{fmt.Sprintf("%s: %s", location, err.Message)}
+	}
+
+	var violations []string
+	for _, cause := range err.Causes {
+		violations = append(violations, schemaViolations(cause)...)
+	}
+	return violations
+}
+
+// validateInput validates the workflow input against the workflow's dataInputSchema. Violations fail the
+// execution unless failOnValidationErrors is false, in which case they are only logged.
+func (e *Engine) validateInput(ctx context.Context, workflow *ServerlessWorkflow, input interface{}) error {
+	if workflow.DataInputSchema == nil || workflow.DataInputSchema.Schema == "" {
+		return nil
+	}
+
+	err := e.validateData(schemaLocation(workflow, workflow.DataInputSchema.Schema), input, "workflow input")
+	if err == nil {
+		return nil
+	}
+	err.WithWorkflow(workflow.ID)
+	if err.Code == ErrDataValidation && !failOnValidationErrors(workflow.DataInputSchema) {
+		e.logger.WarnContextf(ctx, "Ignoring invalid workflow input: %v", err)
+		return nil
+	}
+	e.logger.ErrorContextf(ctx, "Invalid workflow input: %v", err)
+	return err
+}
+
+// schemaLocation resolves the location of a schema referenced by the workflow. Relative paths resolve against the
+// directory of the file the workflow was parsed from, and against the working directory for other workflows.
+func schemaLocation(workflow *ServerlessWorkflow, location string) string {
+	if workflow == nil || filepath.IsAbs(location) || strings.Contains(location, "://") {
+		return location
+	}
+	if workflow.Dir == "" {
+		return location
+	}
+	return filepath.Join(workflow.Dir, location)
+}
+
+// failOnValidationErrors reports whether invalid input fails the execution, which is the default
+func failOnValidationErrors(schema *sw.DataInputSchema) bool {
+	return schema.FailOnValidationErrors == nil || *schema.FailOnValidationErrors
+}
+
+// outputSchema returns the location of the JSON Schema of the state's output, declared in its metadata
+func outputSchema(state sw.State) string {
+	metadata := state.GetMetadata()
+	if metadata == nil {
+		return ""
+	}
+	object, ok := (*metadata)[outputSchemaKey]
+	if !ok {
+		return ""
+	}
+	var location string
+	raw, err := json.Marshal(object)
+	if err != nil || json.Unmarshal(raw, &location) != nil {
+		return ""
+	}
+	return location
+}
+
+// validateStateOutput validates the output of the state against the schema declared in its metadata, if any
+func (e *Engine) validateStateOutput(ctx context.Context, state sw.State, output interface{}) error {
+	location := outputSchema(state)
+	if location == "" {
+		return nil
+	}
+	location = schemaLocation(workflowFromContext(ctx), location)
+
+	if err := e.validateData(location, output, fmt.Sprintf("output of state '%s'", state.GetName())); err != nil {
+		err.WithState(state.GetName())
+		e.logger.ErrorContextf(ctx, "Invalid state output: %v", err)
+		return err
+	}
+	return nil
+}
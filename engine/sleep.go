@@ -41,9 +41,9 @@ func (e *Engine) executeSleepState(ctx context.Context, state *sw.SleepState, da
 	timer := time.NewTimer(duration)
 	select {
 	case <-ctx.Done():
-		if !timer.Stop() {
-			<-timer.C
-		}
+		timer.Stop()
+		e.logger.DebugContext(ctx, "Sleep interrupted")
+		return nil, ctx.Err()
 	case <-timer.C:
 	}
 
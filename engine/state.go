@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -38,38 +39,46 @@ func (e *Engine) executeState(ctx context.Context, state sw.State, data *Workflo
 	var stateExec *StateExecution
 	if e.debugEnabled {
 		stateExec = &StateExecution{
-			Name:      state.GetName(),
-			Type:      string(state.GetType()),
-			StartTime: time.Now(),
-			Input:     data.Current,
+			Name:            state.GetName(),
+			Type:            string(state.GetType()),
+			StartTime:       time.Now(),
+			Input:           data.Current,
+			CompensationFor: compensatedStateFromContext(ctx),
 		}
 		defer func() {
 			stateExec.EndTime = time.Now()
-			e.currentDebug.States = append(e.currentDebug.States, *stateExec)
+			executionFromContext(ctx).recordState(*stateExec)
 			e.logger.DebugContextf(ctx, "State execution completed in %v", stateExec.EndTime.Sub(stateExec.StartTime))
 		}()
 	}
 
-	switch state.GetType() {
-	case sw.StateTypeOperation:
-		e.logger.DebugContext(ctx, "Executing operation state")
-		result, err = e.executeOperationState(ctx, state.(*sw.OperationState), data, stateExec)
-
-	case sw.StateTypeSwitch:
-		e.logger.DebugContext(ctx, "Executing switch state")
-		result, err = e.executeSwitchState(ctx, state.(*sw.SwitchState), data, stateExec)
-
-	case sw.StateTypeSleep:
-		e.logger.DebugContext(ctx, "Executing sleep state")
-		result, err = e.executeSleepState(ctx, state.(*sw.SleepState), data, stateExec)
-
-	default:
-		err = NewWorkflowError(ErrStateInvalid, "Unsupported state type").
-			WithState(state.GetName()).
-			WithContext("stateType", state.GetType())
-		e.logger.ErrorContextf(ctx, "Unsupported state type: %s", state.GetType())
+	stateTimeout, actionTimeout, err := stateTimeouts(workflowFromContext(ctx), state)
+	if err != nil {
+		if stateExec != nil {
+			stateExec.Error = err.Error()
+		}
 		return nil, err
 	}
+	var cancel context.CancelFunc
+	ctx, cancel = withTimeout(ctx, stateExecTimeout, stateTimeout)
+	defer cancel()
+	ctx = withActionTimeout(ctx, actionTimeout)
+
+	// Errors of the input filter, of the state itself, of the output filter and of the output validation are routed
+	// through the state's onErrors. The output of an onErrors handler is not filtered.
+	if err = e.applyStateInputFilter(ctx, state, data); err == nil {
+		result, err = e.executeStateType(ctx, state, data, stateExec)
+	}
+	if err == nil {
+		result.Data, err = e.applyStateOutputFilter(ctx, state, data, result.Data)
+	}
+	if err == nil {
+		err = e.validateStateOutput(ctx, state, result.Data)
+	}
+
+	if err != nil {
+		result, err = e.recoverStateError(ctx, state, data, err)
+	}
 
 	if err != nil {
 		if stateExec != nil {
@@ -79,8 +88,17 @@ func (e *Engine) executeState(ctx context.Context, state sw.State, data *Workflo
 		return nil, err
 	}
 
+	// Switch states and onErrors handlers decide themselves whether their transition compensates
+	if result.Error == nil && state.GetType() != sw.StateTypeSwitch {
+		result.Compensate = compensates(state.GetTransition(), state.GetEnd())
+	}
+
 	if stateExec != nil {
 		stateExec.Output = result.Data
+		if result.Error != nil {
+			// The error was handled by onErrors, keep it visible in the debug data
+			stateExec.Error = result.Error.Error()
+		}
 	}
 
 	e.logger.InfoContextf(ctx, "State '%s' completed successfully", state.GetName())
@@ -89,20 +107,53 @@ func (e *Engine) executeState(ctx context.Context, state sw.State, data *Workflo
 	return result, nil
 }
 
+// executeStateType executes the state according to its type
+func (e *Engine) executeStateType(ctx context.Context, state sw.State, data *WorkflowData, stateExec *StateExecution) (result *StateResult, err error) {
+	switch state.GetType() {
+	case sw.StateTypeOperation:
+		e.logger.DebugContext(ctx, "Executing operation state")
+		result, err = e.executeOperationState(ctx, state.(*sw.OperationState), data, stateExec)
+
+	case sw.StateTypeSwitch:
+		e.logger.DebugContext(ctx, "Executing switch state")
+		result, err = e.executeSwitchState(ctx, state.(*sw.SwitchState), data, stateExec)
+
+	case sw.StateTypeEvent:
+		e.logger.DebugContext(ctx, "Executing event state")
+		result, err = e.executeEventState(ctx, state.(*sw.EventState), data, stateExec)
+
+	case sw.StateTypeCallback:
+		e.logger.DebugContext(ctx, "Executing callback state")
+		result, err = e.executeCallbackState(ctx, state.(*sw.CallbackState), data, stateExec)
+
+	case sw.StateTypeSleep:
+		e.logger.DebugContext(ctx, "Executing sleep state")
+		result, err = e.executeSleepState(ctx, state.(*sw.SleepState), data, stateExec)
+
+	case sw.StateTypeParallel:
+		e.logger.DebugContext(ctx, "Executing parallel state")
+		result, err = e.executeParallelState(ctx, state.(*sw.ParallelState), data, stateExec)
+
+	case sw.StateTypeForEach:
+		e.logger.DebugContext(ctx, "Executing foreach state")
+		result, err = e.executeForEachState(ctx, state.(*sw.ForEachState), data, stateExec)
+
+	case sw.StateTypeInject:
+		e.logger.DebugContext(ctx, "Executing inject state")
+		result, err = e.executeInjectState(ctx, state.(*sw.InjectState), data, stateExec)
+
+	default:
+		err = NewWorkflowError(ErrStateInvalid, "Unsupported state type").
+			WithState(state.GetName()).
+			WithContext("stateType", state.GetType())
+		e.logger.ErrorContextf(ctx, "Unsupported state type: %s", state.GetType())
+	}
+	return result, err
+}
+
 func (e *Engine) executeOperationState(ctx context.Context, state *sw.OperationState, data *WorkflowData, stateExec *StateExecution) (*StateResult, error) {
-	result, err := e.executeActions(ctx, state.Actions, data, stateExec)
+	result, err := e.executeActionsInMode(ctx, state.ActionMode, state.Actions, data, stateExec)
 	if err != nil {
-		// Check for activity error anywhere in the error chain
-		if actErr, ok := UnwrapActivityError(err); ok && len(state.OnErrors) > 0 {
-			nextState, handled := e.handleStateError(ctx, actErr, state.OnErrors)
-			if handled {
-				return &StateResult{
-					Data:      data.Current,
-					NextState: nextState,
-					Error:     err, // Preserve the full error chain
-				}, nil
-			}
-		}
 		return nil, err
 	}
 
@@ -127,3 +178,52 @@ func (e *Engine) executeSwitchState(ctx context.Context, state *sw.SwitchState,
 
 	return nil, fmt.Errorf("switch state must have either data conditions or event conditions")
 }
+
+// recoverStateError routes an error raised while executing a state through the state's onErrors definitions.
+// Any error of the state can be handled: activity errors, timeouts of the state or its actions, and workflow
+// errors such as failed expressions. Errors without a code are handled as STATE_EXECUTION_FAILED. If a handler
+// matches, the workflow transitions to the handler's state, otherwise the error is returned as is.
+func (e *Engine) recoverStateError(ctx context.Context, state sw.State, data *WorkflowData, err error) (*StateResult, error) {
+	if ctx.Err() != nil {
+		timeout := expiredTimeout(ctx)
+		if timeout == nil || timeout.name == workflowExecTimeout {
+			// Cancelled, or the whole workflow ran out of time: there is nothing left to recover
+			return nil, err
+		}
+		if !isTimeoutError(err) {
+			err = timeout.newError(err).WithState(state.GetName())
+		}
+	}
+
+	if len(state.GetOnErrors()) == 0 {
+		return nil, err
+	}
+
+	routed := err
+	var code string
+	var wfErr *WorkflowError
+	if isTimeoutError(err) {
+		code = string(ErrTimeout)
+	} else if actErr, ok := UnwrapActivityError(err); ok {
+		// Check for activity error anywhere in the error chain
+		code = string(actErr.Code)
+	} else if errors.As(err, &wfErr) {
+		code = string(wfErr.Code)
+	} else {
+		code = string(ErrStateExecutionFail)
+		routed = NewWorkflowError(ErrStateExecutionFail, err.Error()).
+			WithState(state.GetName()).
+			WithCause(err)
+	}
+
+	handler, handled := e.handleStateError(ctx, code, routed, state.GetOnErrors())
+	if !handled {
+		return nil, err
+	}
+	return &StateResult{
+		Data:       data.Current,
+		NextState:  handlerNextState(handler),
+		Compensate: compensates(handler.Transition, handler.End),
+		Error:      routed, // Preserve the full error chain
+	}, nil
+}
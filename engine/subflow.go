@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// onParentCompleteContinue lets an async sub-flow keep running after its parent run returned
+const onParentCompleteContinue = "continue"
+
+// executeSubFlow runs the workflow referenced by the action's subFlowRef, resolved from the workflow registry,
+// with its own workflow data initialized from the action's state data. A sync sub-flow's output is the action's
+// result. An async sub-flow is started in the background and has no result; unless its onParentComplete is
+// "continue" it is terminated when the parent run returns.
+func (e *Engine) executeSubFlow(ctx context.Context, action sw.Action, data *WorkflowData, stateExec *StateExecution) (result interface{}, err error) {
+	ref := action.SubFlowRef
+
+	if e.telemetry != nil {
+		var span trace.Span
+		ctx, span = e.telemetry.StartSubFlowSpan(ctx, ref.WorkflowID, ref.Version, string(ref.Invoke))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			} else {
+				span.SetStatus(codes.Ok, "")
+			}
+			span.End()
+		}()
+	}
+
+	e.logger.InfoContextf(ctx, "Executing sub-flow: %s (Invoke: %s)", ref.WorkflowID, ref.Invoke)
+
+	var actionResult *ActionResult
+	if e.debugEnabled && stateExec != nil {
+		actionResult = &ActionResult{
+			WorkflowID: ref.WorkflowID,
+			StartTime:  time.Now(),
+		}
+		defer func() {
+			actionResult.EndTime = time.Now()
+			if err != nil {
+				actionResult.Error = err.Error()
+			}
+			stateExec.Actions = append(stateExec.Actions, *actionResult)
+		}()
+	}
+
+	workflow, ok := e.workflows.Get(ref.WorkflowID, ref.Version)
+	if !ok {
+		e.logger.ErrorContextf(ctx, "Sub-flow '%s' version '%s' not found", ref.WorkflowID, ref.Version)
+		return nil, NewWorkflowError(ErrWorkflowNotFound, fmt.Sprintf("sub-flow '%s' is not registered", ref.WorkflowID)).
+			WithWorkflow(ref.WorkflowID).
+			WithContext("version", ref.Version)
+	}
+
+	if err := e.validateInput(ctx, workflow, data.Current); err != nil {
+		return nil, err
+	}
+
+	// The sub-flow works on its own copy of the data, so that it cannot change the parent's data
+	subFlowData := NewWorkflowData(deepCopy(data.Current), deepCopyMap(data.Globals))
+	exec := newExecution(newRunID(), workflow, e.debugEnabled)
+	if actionResult != nil {
+		actionResult.WorkflowVersion = workflow.Version
+		actionResult.RunID = exec.runID
+		actionResult.Arguments = deepCopy(subFlowData.Current)
+	}
+
+	// States of the sub-flow are not part of a compensation of the parent
+	ctx = withCompensatedState(ctx, "")
+
+	if ref.Invoke == sw.InvokeKindAsync {
+		e.startAsyncSubFlow(ctx, ref, exec, subFlowData)
+		return nil, nil
+	}
+
+	subFlowCtx, cancel := withTimeout(ctx, actionExecTimeout, actionTimeoutFromContext(ctx))
+	defer cancel()
+	subFlowResult, err := e.run(subFlowCtx, exec, subFlowData, startStateName(workflow))
+	if actionResult != nil && subFlowResult != nil {
+		actionResult.SubFlow = subFlowResult.Debug
+	}
+	if err != nil {
+		if timeout := expiredTimeout(subFlowCtx); timeout != nil && ctx.Err() == nil {
+			err = timeout.newError(err)
+		}
+		e.logger.ErrorContextf(ctx, "Sub-flow '%s' failed: %v", ref.WorkflowID, err)
+		return nil, NewWorkflowError(ErrSubFlowExecution, fmt.Sprintf("sub-flow '%s' failed", ref.WorkflowID)).
+			WithWorkflow(ref.WorkflowID).
+			WithContext("runId", exec.runID).
+			WithCause(err)
+	}
+
+	e.logger.InfoContextf(ctx, "Sub-flow '%s' completed", ref.WorkflowID)
+	if actionResult != nil {
+		actionResult.Output = subFlowResult.Data
+	}
+	return subFlowResult.Data, nil
+}
+
+// startAsyncSubFlow runs the sub-flow in the background. The sub-flow is not bound by the parent's timeouts
+// or cancellation, only by the completion of the parent run when onParentComplete is "terminate".
+func (e *Engine) startAsyncSubFlow(ctx context.Context, ref *sw.WorkflowRef, exec *execution, data *WorkflowData) {
+	subFlowCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	if parent := executionFromContext(ctx); parent != nil && ref.OnParentComplete != onParentCompleteContinue {
+		go func() {
+			select {
+			case <-parent.done:
+				e.logger.InfoContextf(subFlowCtx, "Terminating async sub-flow '%s', run %s: parent run completed", ref.WorkflowID, exec.runID)
+				cancel()
+			case <-subFlowCtx.Done():
+			}
+		}()
+	}
+
+	go func() {
+		defer cancel()
+		if _, err := e.run(subFlowCtx, exec, data, startStateName(exec.workflow)); err != nil {
+			e.logger.ErrorContextf(subFlowCtx, "Async sub-flow '%s', run %s failed: %v", ref.WorkflowID, exec.runID, err)
+		}
+	}()
+}
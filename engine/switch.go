@@ -15,16 +15,24 @@ func (e *Engine) executeDataBasedSwitch(ctx context.Context, state *sw.SwitchSta
 		// Evaluate each condition with its own span
 		conditionResult, conditionErr := e.evaluateCondition(ctx, state.GetName(), condition, data)
 		if conditionErr != nil {
-			return nil, conditionErr
+			return nil, NewWorkflowError(ErrExpressionEval, "Failed to evaluate condition").
+				WithState(state.GetName()).
+				WithExpression(condition.Condition).
+				WithCause(conditionErr)
 		}
 
 		if conditionResult {
 			if stateExec != nil {
 				stateExec.MatchedCondition = condition.Condition
 			}
+			var nextState string
+			if condition.Transition != nil {
+				nextState = condition.Transition.NextState
+			}
 			result = &StateResult{
-				Data:      data.Current,
-				NextState: condition.Transition.NextState,
+				Data:       data.Current,
+				NextState:  nextState,
+				Compensate: compensates(condition.Transition, condition.End),
 			}
 			return result, nil
 		}
@@ -49,8 +57,9 @@ func (e *Engine) executeDataBasedSwitch(ctx context.Context, state *sw.SwitchSta
 		stateExec.MatchedCondition = "default"
 	}
 	result = &StateResult{
-		Data:      data.Current,
-		NextState: state.DefaultCondition.Transition.NextState,
+		Data:       data.Current,
+		NextState:  defaultConditionNextState(state.DefaultCondition),
+		Compensate: compensates(state.DefaultCondition.Transition, state.DefaultCondition.End),
 	}
 	return result, nil
 }
@@ -72,8 +81,13 @@ func (e *Engine) evaluateCondition(ctx context.Context, stateName string, condit
 		}()
 	}
 
-	// Parse the JQ query
-	query, err := gojq.Parse(condition.Condition)
+	// Parse the JQ query, in which the workflow's expression functions can be referenced
+	expanded, err := expandFunctions(ctx, condition.Condition)
+	if err != nil {
+		e.logger.ErrorContextf(ctx, "failed to expand condition '%s': %v", condition.Condition, err)
+		return false, err
+	}
+	query, err := gojq.Parse(expanded)
 	if err != nil {
 		e.logger.ErrorContextf(ctx, "failed to parse condition '%s': %v", condition.Condition, err)
 		return false, err
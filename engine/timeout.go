@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+)
+
+// Names of the timeouts, as used in the workflow definition
+const (
+	workflowExecTimeout = "workflowExecTimeout"
+	stateExecTimeout    = "stateExecTimeout"
+	actionExecTimeout   = "actionExecTimeout"
+	eventTimeoutName    = "eventTimeout"
+)
+
+// actionTimeoutKey is the context key under which the actionExecTimeout of the running state is stored
+type actionTimeoutKey struct{}
+
+// parseTimeout parses an ISO 8601 timeout. An empty or "unlimited" timeout is returned as 0.
+func parseTimeout(name string, value string) (time.Duration, error) {
+	if value == "" || value == sw.UnlimitedTimeout {
+		return 0, nil
+	}
+
+	timeout, err := utils.ParseISODuration(value)
+	if err != nil {
+		return 0, NewWorkflowError(ErrWorkflowInvalid, fmt.Sprintf("invalid %s '%s'", name, value)).
+			WithContext("timeout", name).
+			WithCause(err)
+	}
+	return timeout, nil
+}
+
+// timeoutCause is the cause of a context that expired because of one of the workflow's timeouts
+type timeoutCause struct {
+	name    string
+	timeout time.Duration
+}
+
+func (c *timeoutCause) Error() string {
+	return fmt.Sprintf("%s of %v exceeded", c.name, c.timeout)
+}
+
+// newError returns the timeout error reported to the workflow, wrapping the error the timeout caused
+func (c *timeoutCause) newError(err error) *WorkflowError {
+	return NewWorkflowError(ErrTimeout, c.Error()).
+		WithContext("timeout", c.name).
+		WithContext("duration", c.timeout.String()).
+		WithCause(err)
+}
+
+// withTimeout derives a context that expires after the timeout. The context's cause records which timeout
+// expired, so that it can be told apart from cancellation. A timeout of 0 returns the context unchanged.
+func withTimeout(ctx context.Context, name string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, timeout, &timeoutCause{name: name, timeout: timeout})
+}
+
+// expiredTimeout returns the timeout that made the context expire, or nil if the context is still
+// live or was cancelled for another reason
+func expiredTimeout(ctx context.Context) *timeoutCause {
+	if ctx.Err() == nil {
+		return nil
+	}
+	var cause *timeoutCause
+	if errors.As(context.Cause(ctx), &cause) {
+		return cause
+	}
+	return nil
+}
+
+// isTimeoutError reports whether err is, or wraps, a timeout error
+func isTimeoutError(err error) bool {
+	var wfErr *WorkflowError
+	return errors.As(err, &wfErr) && wfErr.Code == ErrTimeout
+}
+
+// workflowTimeout returns the workflowExecTimeout of the workflow and its parsed duration, or nil if the
+// workflow's execution time is unlimited
+func workflowTimeout(workflow *ServerlessWorkflow) (*sw.WorkflowExecTimeout, time.Duration, error) {
+	if workflow.Timeouts == nil || workflow.Timeouts.WorkflowExecTimeout == nil {
+		return nil, 0, nil
+	}
+
+	execTimeout := workflow.Timeouts.WorkflowExecTimeout
+	timeout, err := parseTimeout(workflowExecTimeout, execTimeout.Duration)
+	if err != nil || timeout == 0 {
+		return nil, 0, err
+	}
+	return execTimeout, timeout, nil
+}
+
+// stateTimeouts returns the stateExecTimeout and actionExecTimeout that apply to the state.
+// Timeouts defined on the state take precedence over the ones defined on the workflow.
+func stateTimeouts(workflow *ServerlessWorkflow, state sw.State) (stateTimeout time.Duration, actionTimeout time.Duration, err error) {
+	var stateExec *sw.StateExecTimeout
+	var actionExec string
+	if workflow != nil && workflow.Timeouts != nil {
+		stateExec = workflow.Timeouts.StateExecTimeout
+		actionExec = workflow.Timeouts.ActionExecTimeout
+	}
+
+	switch s := state.(type) {
+	case *sw.OperationState:
+		if s.Timeouts != nil {
+			stateExec, actionExec = overrideTimeouts(stateExec, actionExec, s.Timeouts.StateExecTimeout, s.Timeouts.ActionExecTimeout)
+		}
+	case *sw.ForEachState:
+		if s.Timeouts != nil {
+			stateExec, actionExec = overrideTimeouts(stateExec, actionExec, s.Timeouts.StateExecTimeout, s.Timeouts.ActionExecTimeout)
+		}
+	case *sw.EventState:
+		if s.Timeouts != nil {
+			stateExec, actionExec = overrideTimeouts(stateExec, actionExec, s.Timeouts.StateExecTimeout, s.Timeouts.ActionExecTimeout)
+		}
+	case *sw.CallbackState:
+		if s.Timeouts != nil {
+			stateExec, actionExec = overrideTimeouts(stateExec, actionExec, s.Timeouts.StateExecTimeout, s.Timeouts.ActionExecTimeout)
+		}
+	case *sw.ParallelState:
+		if s.Timeouts != nil {
+			stateExec, _ = overrideTimeouts(stateExec, "", s.Timeouts.StateExecTimeout, "")
+		}
+	case *sw.SwitchState:
+		if s.Timeouts != nil {
+			stateExec, _ = overrideTimeouts(stateExec, "", s.Timeouts.StateExecTimeout, "")
+		}
+	case *sw.SleepState:
+		if s.Timeouts != nil {
+			stateExec, _ = overrideTimeouts(stateExec, "", s.Timeouts.StateExecTimeout, "")
+		}
+	case *sw.InjectState:
+		if s.Timeouts != nil {
+			stateExec, _ = overrideTimeouts(stateExec, "", s.Timeouts.StateExecTimeout, "")
+		}
+	}
+
+	if stateExec != nil {
+		if stateTimeout, err = parseTimeout(stateExecTimeout, stateExec.Total); err != nil {
+			return 0, 0, err
+		}
+	}
+	if actionTimeout, err = parseTimeout(actionExecTimeout, actionExec); err != nil {
+		return 0, 0, err
+	}
+	return stateTimeout, actionTimeout, nil
+}
+
+// eventTimeout returns how long the state waits for events. The eventTimeout defined on the state takes
+// precedence over the one defined on the workflow. A timeout of 0 means the state waits indefinitely.
+func eventTimeout(workflow *ServerlessWorkflow, state sw.State) (time.Duration, error) {
+	var timeout string
+	if workflow != nil && workflow.Timeouts != nil {
+		timeout = workflow.Timeouts.EventTimeout
+	}
+
+	switch s := state.(type) {
+	case *sw.SwitchState:
+		if s.Timeouts != nil && s.Timeouts.EventTimeout != "" {
+			timeout = s.Timeouts.EventTimeout
+		}
+	case *sw.EventState:
+		if s.Timeouts != nil && s.Timeouts.EventTimeout != "" {
+			timeout = s.Timeouts.EventTimeout
+		}
+	case *sw.CallbackState:
+		if s.Timeouts != nil && s.Timeouts.EventTimeout != "" {
+			timeout = s.Timeouts.EventTimeout
+		}
+	}
+
+	return parseTimeout(eventTimeoutName, timeout)
+}
+
+// overrideTimeouts returns the state level timeouts where they are set, and the workflow level ones otherwise
+func overrideTimeouts(stateExec *sw.StateExecTimeout, actionExec string, stateLevel *sw.StateExecTimeout, stateLevelAction string) (*sw.StateExecTimeout, string) {
+	if stateLevel != nil {
+		stateExec = stateLevel
+	}
+	if stateLevelAction != "" {
+		actionExec = stateLevelAction
+	}
+	return stateExec, actionExec
+}
+
+// withActionTimeout returns a context carrying the actionExecTimeout of the running state
+func withActionTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, actionTimeoutKey{}, timeout)
+}
+
+// actionTimeoutFromContext returns the actionExecTimeout of the running state, or 0 if there is none
+func actionTimeoutFromContext(ctx context.Context) time.Duration {
+	timeout, _ := ctx.Value(actionTimeoutKey{}).(time.Duration)
+	return timeout
+}
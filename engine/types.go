@@ -1,12 +1,14 @@
 package engine
 
-import sw "github.com/serverlessworkflow/sdk-go/v2/model"
+import "github.com/kshitiz1403/jsonjuggler/parser"
 
-type ServerlessWorkflow = sw.Workflow
+type ServerlessWorkflow = parser.Workflow
 
 // StateResult represents the result of a state execution
 type StateResult struct {
 	Data      interface{}
 	NextState string
-	Error     error
+	// Compensate is set when the transition or end taken triggers compensation
+	Compensate bool
+	Error      error
 }
@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kshitiz1403/jsonjuggler/parser"
+)
+
+// WorkflowRegistry holds the workflow definitions that are executed by ID and that sub-flows are resolved from,
+// indexed by ID and version. Registering never modifies the index in place, it swaps in an updated copy, so
+// lookups always see a consistent set of workflows.
+type WorkflowRegistry struct {
+	mu sync.RWMutex
+	// workflows maps a workflow ID to its definitions by version
+	workflows workflowIndex
+}
+
+// workflowIndex maps a workflow ID to its definitions by version
+type workflowIndex map[string]map[string]*ServerlessWorkflow
+
+// NewWorkflowRegistry creates an empty workflow registry
+func NewWorkflowRegistry() *WorkflowRegistry {
+	return &WorkflowRegistry{
+		workflows: make(workflowIndex),
+	}
+}
+
+// Register adds workflow definitions to the registry. Every workflow must have an ID, and each version of a
+// workflow can only be registered once. Either all the workflows are registered or none of them is.
+func (r *WorkflowRegistry) Register(workflows ...*ServerlessWorkflow) error {
+	return r.update(func(index workflowIndex) error {
+		for _, workflow := range workflows {
+			if err := index.add(workflow); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Load registers workflow definitions like Register, and additionally checks that every sub-flow they reference
+// is registered or among the loaded workflows
+func (r *WorkflowRegistry) Load(workflows ...*ServerlessWorkflow) error {
+	return r.update(func(index workflowIndex) error {
+		for _, workflow := range workflows {
+			if err := index.add(workflow); err != nil {
+				return err
+			}
+		}
+		for _, workflow := range workflows {
+			if err := index.checkSubFlows(workflow); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Replace atomically swaps a registered workflow definition for a new one, e.g. an edited definition of the
+// same version. Either workflow may be nil to only register or unregister a definition. The new definition is
+// checked like with Load, and the change is rejected if a registered workflow references the previous definition
+// as a sub-flow that no longer resolves. Runs that already started keep executing the definition they started with.
+func (r *WorkflowRegistry) Replace(previous, workflow *ServerlessWorkflow) error {
+	return r.update(func(index workflowIndex) error {
+		if previous != nil {
+			index.remove(previous)
+		}
+		if workflow != nil {
+			if err := index.add(workflow); err != nil {
+				return err
+			}
+			if err := index.checkSubFlows(workflow); err != nil {
+				return err
+			}
+		}
+		if previous != nil {
+			return index.checkDependents(previous.ID)
+		}
+		return nil
+	})
+}
+
+// update applies the changes to a copy of the index and swaps it in if all of them succeed
+func (r *WorkflowRegistry) update(apply func(index workflowIndex) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := r.workflows.clone()
+	if err := apply(index); err != nil {
+		return err
+	}
+	r.workflows = index
+	return nil
+}
+
+// Get returns the workflow with the given ID and version. Without a version the latest version is returned.
+func (r *WorkflowRegistry) Get(id, version string) (*ServerlessWorkflow, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.workflows.get(id, version)
+}
+
+// Versions returns the registered versions of the workflow, from oldest to latest
+func (r *WorkflowRegistry) Versions(id string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := make([]string, 0, len(r.workflows[id]))
+	for version := range r.workflows[id] {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// clone returns a copy of the index that can be modified without affecting the original
+func (index workflowIndex) clone() workflowIndex {
+	cloned := make(workflowIndex, len(index))
+	for id, versions := range index {
+		cloned[id] = make(map[string]*ServerlessWorkflow, len(versions))
+		for version, workflow := range versions {
+			cloned[id][version] = workflow
+		}
+	}
+	return cloned
+}
+
+// add indexes the workflow, unless it has no ID or its version is already indexed
+func (index workflowIndex) add(workflow *ServerlessWorkflow) error {
+	if workflow == nil || workflow.ID == "" {
+		return NewWorkflowError(ErrWorkflowInvalid, "workflow must have an id to be registered")
+	}
+
+	versions, ok := index[workflow.ID]
+	if !ok {
+		versions = make(map[string]*ServerlessWorkflow)
+		index[workflow.ID] = versions
+	}
+	if _, exists := versions[workflow.Version]; exists {
+		return NewWorkflowError(ErrWorkflowInvalid, fmt.Sprintf("workflow '%s' version '%s' is already registered", workflow.ID, workflow.Version)).
+			WithWorkflow(workflow.ID)
+	}
+	versions[workflow.Version] = workflow
+	return nil
+}
+
+// remove removes the workflow from the index, if it is indexed
+func (index workflowIndex) remove(workflow *ServerlessWorkflow) {
+	versions := index[workflow.ID]
+	if versions[workflow.Version] != workflow {
+		return
+	}
+	delete(versions, workflow.Version)
+	if len(versions) == 0 {
+		delete(index, workflow.ID)
+	}
+}
+
+// get returns the workflow with the given ID and version, or the latest version without a version
+func (index workflowIndex) get(id, version string) (*ServerlessWorkflow, bool) {
+	versions, ok := index[id]
+	if !ok {
+		return nil, false
+	}
+	if version != "" {
+		workflow, ok := versions[version]
+		return workflow, ok
+	}
+
+	var latest string
+	for candidate := range versions {
+		if latest == "" || compareVersions(candidate, latest) > 0 {
+			latest = candidate
+		}
+	}
+	workflow, ok := versions[latest]
+	return workflow, ok
+}
+
+// checkSubFlows returns an error if the workflow references a sub-flow that is not in the index
+func (index workflowIndex) checkSubFlows(workflow *ServerlessWorkflow) error {
+	for _, state := range workflow.States {
+		for _, action := range parser.StateActions(state) {
+			if action.SubFlowRef == nil {
+				continue
+			}
+			if _, ok := index.get(action.SubFlowRef.WorkflowID, action.SubFlowRef.Version); !ok {
+				return NewWorkflowError(ErrWorkflowNotFound, fmt.Sprintf("sub-flow '%s' version '%s' is not registered", action.SubFlowRef.WorkflowID, action.SubFlowRef.Version)).
+					WithWorkflow(workflow.ID).
+					WithState(state.GetName())
+			}
+		}
+	}
+	return nil
+}
+
+// checkDependents returns an error if a workflow in the index references a sub-flow with the ID that is not in
+// the index
+func (index workflowIndex) checkDependents(id string) error {
+	ids := make([]string, 0, len(index))
+	for dependent := range index {
+		ids = append(ids, dependent)
+	}
+	sort.Strings(ids)
+
+	for _, dependent := range ids {
+		for _, workflow := range index[dependent] {
+			for _, state := range workflow.States {
+				for _, action := range parser.StateActions(state) {
+					if action.SubFlowRef == nil || action.SubFlowRef.WorkflowID != id {
+						continue
+					}
+					if _, ok := index.get(id, action.SubFlowRef.Version); !ok {
+						return NewWorkflowError(ErrWorkflowNotFound, fmt.Sprintf("sub-flow '%s' version '%s' used by workflow version '%s' would no longer be registered", id, action.SubFlowRef.Version, workflow.Version)).
+							WithWorkflow(workflow.ID).
+							WithState(state.GetName())
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// compareVersions compares dot separated versions such as "1.10.2" segment by segment, numerically where
+// both segments are numbers. It returns a negative number, zero or a positive number when a is lower than,
+// equal to or greater than b.
+func compareVersions(a, b string) int {
+	aSegments := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bSegments := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(aSegments) || i < len(bSegments); i++ {
+		var aSegment, bSegment string
+		if i < len(aSegments) {
+			aSegment = aSegments[i]
+		}
+		if i < len(bSegments) {
+			bSegment = bSegments[i]
+		}
+
+		aNumber, aErr := strconv.Atoi(aSegment)
+		bNumber, bErr := strconv.Atoi(bSegment)
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNumber != bNumber {
+				return aNumber - bNumber
+			}
+		case aSegment != bSegment:
+			return strings.Compare(aSegment, bSegment)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,227 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/kshitiz1403/jsonjuggler/events"
+)
+
+// defaultMaxPending is the default number of undelivered events the bus keeps
+const defaultMaxPending = 1000
+
+// subscriptionBuffer is the number of events a subscription buffers before Publish blocks
+const subscriptionBuffer = 64
+
+// ErrClosed is returned when publishing to or subscribing on a closed bus
+var ErrClosed = errors.New("event bus closed")
+
+// Bus is an in-process event bus based on channels. Events are delivered to every subscription they
+// match. Events that match no subscription are kept, up to a limit, and delivered to the first
+// subscription they match, so an event published just before a workflow starts waiting is not lost.
+// Events a subscription did not receive before it was closed are kept the same way.
+type Bus struct {
+	mu            sync.Mutex
+	subscriptions map[*subscription]struct{}
+	pending       []events.Event
+	maxPending    int
+	closed        bool
+}
+
+// Option configures a Bus
+type Option func(*Bus)
+
+// WithMaxPending sets the number of undelivered events the bus keeps. The oldest events are dropped first.
+func WithMaxPending(maxPending int) Option {
+	return func(b *Bus) {
+		b.maxPending = maxPending
+	}
+}
+
+// New creates an in-process event bus
+func New(opts ...Option) *Bus {
+	b := &Bus{
+		subscriptions: make(map[*subscription]struct{}),
+		maxPending:    defaultMaxPending,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish delivers the event to all matching subscriptions, or keeps it until a matching subscription is made
+func (b *Bus) Publish(ctx context.Context, event events.Event) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+
+	var matching []*subscription
+	for sub := range b.subscriptions {
+		if sub.matches(event) {
+			matching = append(matching, sub)
+		}
+	}
+	if len(matching) == 0 {
+		b.keep(event)
+		b.mu.Unlock()
+		return nil
+	}
+	b.mu.Unlock()
+
+	for _, sub := range matching {
+		if err := sub.deliver(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a subscription receiving the events matching any of the filters,
+// starting with matching events that were published before
+func (b *Bus) Subscribe(ctx context.Context, filters ...events.Filter) (events.Subscription, error) {
+	sub := &subscription{
+		bus:     b,
+		filters: filters,
+		events:  make(chan events.Event, subscriptionBuffer),
+		done:    make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	remaining := b.pending[:0]
+	for _, event := range b.pending {
+		if sub.matches(event) && len(sub.events) < cap(sub.events) {
+			sub.events <- event
+			continue
+		}
+		remaining = append(remaining, event)
+	}
+	b.pending = remaining
+	b.subscriptions[sub] = struct{}{}
+	return sub, nil
+}
+
+// Close closes all subscriptions and rejects further events
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subscriptions {
+		sub.stop()
+		delete(b.subscriptions, sub)
+	}
+	b.pending = nil
+	return nil
+}
+
+// keep stores an event no subscription matched. Must be called with the lock held.
+func (b *Bus) keep(event events.Event) {
+	if b.closed || b.maxPending <= 0 {
+		return
+	}
+	if len(b.pending) >= b.maxPending {
+		b.pending = b.pending[1:]
+	}
+	b.pending = append(b.pending, event)
+}
+
+// remove unregisters a subscription
+func (b *Bus) remove(sub *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscriptions, sub)
+}
+
+// release stores events a closed subscription did not receive, for the subscriptions made later
+func (b *Bus) release(released ...events.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, event := range released {
+		b.keep(event)
+	}
+}
+
+// subscription is a Bus subscription
+type subscription struct {
+	bus      *Bus
+	filters  []events.Filter
+	events   chan events.Event
+	done     chan struct{}
+	stopOnce sync.Once
+	// mu orders deliveries before the subscription is closed, so that every event that was not received
+	// is released back to the bus
+	mu     sync.Mutex
+	closed bool
+}
+
+func (s *subscription) Events() <-chan events.Event {
+	return s.events
+}
+
+// Close stops the delivery of events and releases the events that were delivered but not received back to the
+// bus, so that later subscriptions can receive them
+func (s *subscription) Close() error {
+	s.bus.remove(s)
+	s.stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var unreceived []events.Event
+	for len(s.events) > 0 {
+		unreceived = append(unreceived, <-s.events)
+	}
+	s.bus.release(unreceived...)
+	return nil
+}
+
+// stop ends the delivery of events. The events channel is never closed, so that a concurrent
+// Publish cannot send on a closed channel.
+func (s *subscription) stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// matches reports whether the event passes any of the subscription's filters
+func (s *subscription) matches(event events.Event) bool {
+	for _, filter := range s.filters {
+		if filter.Matches(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends the event to the subscriber, waiting while its buffer is full. An event for a subscription that
+// is closed meanwhile is released back to the bus.
+func (s *subscription) deliver(ctx context.Context, event events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		s.bus.release(event)
+		return nil
+	}
+
+	select {
+	case s.events <- event:
+		return nil
+	case <-s.done:
+		s.bus.release(event)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,86 @@
+package channel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/events"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, sub events.Subscription) events.Event {
+	t.Helper()
+	select {
+	case event := <-sub.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return events.Event{}
+	}
+}
+
+func TestBus(t *testing.T) {
+	ctx := context.Background()
+	bus := New()
+	defer bus.Close()
+
+	paid := events.Event{ID: "1", Type: "paid", Extensions: map[string]string{"orderid": "A-1"}}
+	otherOrder := events.Event{ID: "2", Type: "paid", Extensions: map[string]string{"orderid": "B-2"}}
+
+	// Events published before anyone subscribed are kept
+	require.NoError(t, bus.Publish(ctx, otherOrder))
+	require.NoError(t, bus.Publish(ctx, paid))
+
+	sub, err := bus.Subscribe(ctx, events.Filter{Type: "paid", Correlation: map[string]string{"orderid": "A-1"}})
+	require.NoError(t, err)
+	require.Equal(t, "1", receive(t, sub).ID)
+
+	// Live events are delivered to matching subscriptions only
+	require.NoError(t, bus.Publish(ctx, events.Event{ID: "3", Type: "shipped", Extensions: map[string]string{"orderid": "A-1"}}))
+	require.NoError(t, bus.Publish(ctx, events.Event{ID: "4", Type: "paid", Extensions: map[string]string{"orderid": "A-1"}}))
+	require.Equal(t, "4", receive(t, sub).ID)
+	require.NoError(t, sub.Close())
+
+	// The event of the other order is still pending
+	anyOrder, err := bus.Subscribe(ctx, events.Filter{Type: "paid", Correlation: map[string]string{"orderid": ""}})
+	require.NoError(t, err)
+	require.Equal(t, "2", receive(t, anyOrder).ID)
+
+	require.NoError(t, bus.Close())
+	require.ErrorIs(t, bus.Publish(ctx, paid), ErrClosed)
+}
+
+func TestMaxPending(t *testing.T) {
+	ctx := context.Background()
+	bus := New(WithMaxPending(1))
+	defer bus.Close()
+
+	require.NoError(t, bus.Publish(ctx, events.Event{ID: "1", Type: "paid"}))
+	require.NoError(t, bus.Publish(ctx, events.Event{ID: "2", Type: "paid"}))
+
+	// The oldest event was dropped
+	sub, err := bus.Subscribe(ctx, events.Filter{Type: "paid"})
+	require.NoError(t, err)
+	require.Equal(t, "2", receive(t, sub).ID)
+	require.Empty(t, sub.Events())
+}
+
+func TestCloseReleasesUnreceivedEvents(t *testing.T) {
+	ctx := context.Background()
+	bus := New()
+	defer bus.Close()
+
+	sub, err := bus.Subscribe(ctx, events.Filter{Type: "paid"})
+	require.NoError(t, err)
+	require.NoError(t, bus.Publish(ctx, events.Event{ID: "1", Type: "paid"}))
+	require.NoError(t, bus.Publish(ctx, events.Event{ID: "2", Type: "paid"}))
+	require.Equal(t, "1", receive(t, sub).ID)
+	require.NoError(t, sub.Close())
+
+	// The event the closed subscription did not receive is delivered to the next one
+	next, err := bus.Subscribe(ctx, events.Filter{Type: "paid"})
+	require.NoError(t, err)
+	require.Equal(t, "2", receive(t, next).ID)
+	require.Empty(t, next.Events())
+}
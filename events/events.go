@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// Event is a CloudEvents style event consumed by workflows
+type Event struct {
+	// ID identifies the event
+	ID string `json:"id,omitempty"`
+	// Source is the CloudEvent source
+	Source string `json:"source,omitempty"`
+	// Type is the CloudEvent type
+	Type string `json:"type"`
+	// Time is the time the event occurred
+	Time time.Time `json:"time,omitempty"`
+	// Extensions are the extension context attributes of the event, used for correlation
+	Extensions map[string]string `json:"extensions,omitempty"`
+	// Data is the event payload
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Filter selects the events a subscription receives
+type Filter struct {
+	// Type is the CloudEvent type the event must have
+	Type string
+	// Source is the CloudEvent source the event must have, any source matches when empty
+	Source string
+	// Correlation maps extension context attribute names to the value the event must have.
+	// An empty value only requires the attribute to be present.
+	Correlation map[string]string
+}
+
+// Matches reports whether the event passes the filter
+func (f Filter) Matches(event Event) bool {
+	if event.Type != f.Type {
+		return false
+	}
+	if f.Source != "" && event.Source != f.Source {
+		return false
+	}
+	for name, value := range f.Correlation {
+		actual, ok := event.Extensions[name]
+		if !ok || (value != "" && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscription delivers the events matching its filters
+type Subscription interface {
+	// Events returns the channel the matching events are delivered on
+	Events() <-chan Event
+	// Close stops the delivery of events. Events that were delivered but not received are handed back to the
+	// bus, for the subscriptions made later.
+	Close() error
+}
+
+// Bus is the event source workflows wait on. Implementations must be safe for concurrent use.
+type Bus interface {
+	// Publish delivers the event to the subscriptions it matches
+	Publish(ctx context.Context, event Event) error
+	// Subscribe returns a subscription receiving the events matching any of the filters
+	Subscribe(ctx context.Context, filters ...Filter) (Subscription, error)
+}
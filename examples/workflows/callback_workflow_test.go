@@ -0,0 +1,230 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/persistence"
+	"github.com/kshitiz1403/jsonjuggler/persistence/memory"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ApprovalActivity sends an approval request to an external system, which is handed the ID of the run
+// to call back
+type ApprovalActivity struct {
+	activities.BaseActivity
+	onRequest func(runID string)
+}
+
+func (a *ApprovalActivity) Execute(ctx context.Context, args map[string]any) (interface{}, error) {
+	if a.onRequest != nil {
+		a.onRequest(engine.RunIDFromContext(ctx))
+	}
+
+	data := cast.ToStringMap(args["data"])
+	data["approvalRequested"] = true
+	return data, nil
+}
+
+func newCallbackEngine(t *testing.T, approval *ApprovalActivity) *engine.Engine {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("RequestApproval", approval),
+	)
+	require.NoError(t, err)
+	return e
+}
+
+func TestCallbackWorkflow(t *testing.T) {
+	decision := map[string]interface{}{"approved": true, "by": "manager"}
+
+	tests := []struct {
+		name string
+		// respond delivers the callback for the run, or does nothing to let the state time out
+		respond  func(e *engine.Engine, runID string)
+		sync     bool
+		expected map[string]interface{}
+		events   int
+	}{
+		{
+			name: "Approved",
+			respond: func(e *engine.Engine, runID string) {
+				assert.NoError(t, e.Callback(context.Background(), runID, decision))
+			},
+			expected: map[string]interface{}{
+				"request":           "R-1",
+				"approvalRequested": true,
+				"approval":          decision,
+			},
+			events: 1,
+		},
+		{
+			// The external system answers before the action has even returned
+			name: "Approved During Action",
+			sync: true,
+			respond: func(e *engine.Engine, runID string) {
+				assert.NoError(t, e.Callback(context.Background(), runID, decision))
+			},
+			expected: map[string]interface{}{
+				"request":           "R-1",
+				"approvalRequested": true,
+				"approval":          decision,
+			},
+			events: 1,
+		},
+		{
+			name:    "No Decision",
+			respond: func(e *engine.Engine, runID string) {},
+			expected: map[string]interface{}{
+				"request":           "R-1",
+				"approvalRequested": true,
+				"status":            "escalated",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e *engine.Engine
+			approval := &ApprovalActivity{}
+			approval.onRequest = func(runID string) {
+				if tt.sync {
+					tt.respond(e, runID)
+					return
+				}
+				go tt.respond(e, runID)
+			}
+			e = newCallbackEngine(t, approval)
+
+			workflow, err := parser.NewParser(e.GetRegistry()).ParseFromFile("callback_workflow.json")
+			require.NoError(t, err)
+
+			result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"request": "R-1"}, nil)
+			require.NoError(t, err)
+
+			writeToFile("outputs/callback_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+			writeToFile("outputs/callback_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			require.Equal(t, tt.expected, result.Data)
+
+			state := result.Debug.States[0]
+			require.Equal(t, "RequestApproval", state.Name)
+			require.Len(t, state.Actions, 1)
+			require.Len(t, state.Events, tt.events)
+			if tt.events == 0 {
+				require.Contains(t, state.Error, string(engine.ErrTimeout))
+			}
+
+			// The run is no longer waiting
+			err = e.Callback(context.Background(), result.RunID, decision)
+			require.ErrorContains(t, err, string(engine.ErrRunNotFound))
+		})
+	}
+}
+
+func TestResumeWaitingCallback(t *testing.T) {
+	store := memory.New()
+	decision := map[string]interface{}{"approved": true, "by": "manager"}
+
+	// First process: stopped while waiting for the decision
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	requested := 0
+	first, err := config.Initialize(
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("RequestApproval", &ApprovalActivity{onRequest: func(string) {
+			requested++
+			time.AfterFunc(100*time.Millisecond, cancel)
+		}}),
+		config.WithPersistence(store),
+	)
+	require.NoError(t, err)
+
+	workflow, err := parser.NewParser(first.GetRegistry()).ParseFromFile("callback_workflow.json")
+	require.NoError(t, err)
+	result, err := first.Execute(ctx, workflow, map[string]interface{}{"request": "R-1"}, nil)
+	require.Error(t, err)
+	require.Equal(t, 1, requested)
+
+	checkpoint, err := store.Load(context.Background(), result.RunID)
+	require.NoError(t, err)
+	require.Equal(t, "RequestApproval", checkpoint.NextState)
+	require.Equal(t, &persistence.Callback{
+		State: "RequestApproval",
+		Data:  map[string]interface{}{"request": "R-1", "approvalRequested": true},
+	}, checkpoint.Callback)
+
+	// Second process: waits for the decision again without requesting the approval again
+	second, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("RequestApproval", &ApprovalActivity{onRequest: func(string) {
+			requested++
+		}}),
+		config.WithPersistence(store),
+	)
+	require.NoError(t, err)
+
+	resumed := make(chan *engine.ExecutionResult, 1)
+	go func() {
+		result, err := second.Resume(context.Background(), result.RunID)
+		assert.NoError(t, err)
+		resumed <- result
+	}()
+	require.Eventually(t, func() bool {
+		return second.Callback(context.Background(), result.RunID, decision) == nil
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case result := <-resumed:
+		require.Equal(t, map[string]interface{}{
+			"request":           "R-1",
+			"approvalRequested": true,
+			"approval":          decision,
+		}, result.Data)
+		require.Len(t, result.Debug.States[0].Actions, 0)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the resumed run did not complete")
+	}
+	require.Equal(t, 1, requested)
+}
+
+func TestCallbackDiscardingData(t *testing.T) {
+	var e *engine.Engine
+	e = newCallbackEngine(t, &ApprovalActivity{onRequest: func(runID string) {
+		go func() {
+			assert.NoError(t, e.Callback(context.Background(), runID, map[string]interface{}{"approved": true}))
+		}()
+	}})
+
+	// The callback only signals the approval, its payload is not merged
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromBytes([]byte(`{
+		"id": "approval", "specVersion": "0.8", "start": "RequestApproval",
+		"events": [{ "name": "ApprovalDecision", "source": "approvals", "type": "com.approvals.decision" }],
+		"functions": [{ "name": "RequestApproval", "operation": "RequestApproval" }],
+		"states": [{
+			"name": "RequestApproval", "type": "callback", "end": true,
+			"action": { "functionRef": { "refName": "RequestApproval", "arguments": { "data": "${ .current }" } } },
+			"eventRef": "ApprovalDecision",
+			"eventDataFilter": { "useData": false },
+			"timeouts": { "eventTimeout": "PT2S" }
+		}]
+	}`))
+	require.NoError(t, err)
+
+	result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"request": "R-1"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"request": "R-1", "approvalRequested": true}, result.Data)
+	require.Len(t, result.Debug.States[0].Events, 1)
+}
@@ -0,0 +1,186 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompensationWorkflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		failOnce map[string]bool
+		executed []string
+		states   []string
+		validate func(t *testing.T, data interface{}, err error)
+	}{
+		{
+			name:     "Order Shipped",
+			executed: []string{"reserve", "charge", "ship"},
+			states:   []string{"ReserveStock", "ChargeCard", "ShipOrder"},
+			validate: func(t *testing.T, data interface{}, err error) {
+				require.NoError(t, err)
+				require.Equal(t, map[string]interface{}{
+					"orderId": "A-1",
+					"reserve": true,
+					"charge":  true,
+					"ship":    true,
+				}, data)
+			},
+		},
+		{
+			name:     "Shipping Failed",
+			failOnce: map[string]bool{"ship": true},
+			executed: []string{"reserve", "charge", "ship", "refund", "notify", "release"},
+			states:   []string{"ReserveStock", "ChargeCard", "ShipOrder", "RefundCard", "NotifyRefund", "ReleaseStock", "CancelOrder"},
+			validate: func(t *testing.T, data interface{}, err error) {
+				require.NoError(t, err)
+				require.Equal(t, map[string]interface{}{
+					"orderId": "A-1",
+					"reserve": true,
+					"charge":  true,
+					"status":  "cancelled",
+				}, data)
+			},
+		},
+		{
+			name:     "Charge Failed",
+			failOnce: map[string]bool{"charge": true},
+			executed: []string{"reserve", "charge", "release"},
+			states:   []string{"ReserveStock", "ChargeCard", "ReleaseStock"},
+			validate: func(t *testing.T, data interface{}, err error) {
+				require.ErrorContains(t, err, "error executing state ChargeCard")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &StepActivity{failOnce: tt.failOnce}
+			engine, err := config.Initialize(
+				config.WithDebug(true),
+				config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+				config.WithActivity("Step", step),
+			)
+			require.NoError(t, err)
+
+			workflow, err := parser.NewParser(engine.GetRegistry()).ParseFromFile("compensation_workflow.json")
+			require.NoError(t, err)
+
+			result, err := engine.Execute(context.Background(), workflow, map[string]interface{}{"orderId": "A-1"}, nil)
+
+			writeToFile("outputs/compensation_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+			writeToFile("outputs/compensation_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			require.Equal(t, tt.executed, step.executed)
+
+			var states []string
+			compensationFor := make(map[string]string)
+			for _, state := range result.Debug.States {
+				states = append(states, state.Name)
+				if state.CompensationFor != "" {
+					compensationFor[state.Name] = state.CompensationFor
+				}
+			}
+			require.Equal(t, tt.states, states)
+			for name, compensated := range compensationFor {
+				switch name {
+				case "ReleaseStock":
+					require.Equal(t, "ReserveStock", compensated)
+				case "RefundCard", "NotifyRefund":
+					require.Equal(t, "ChargeCard", compensated)
+				default:
+					t.Fatalf("state %s is not a compensation state", name)
+				}
+			}
+			tt.validate(t, result.Data, err)
+		})
+	}
+}
+
+func TestCompensationOnWorkflowTimeout(t *testing.T) {
+	tests := []struct {
+		name      string
+		interrupt bool
+		seconds   int
+	}{
+		{
+			name:      "Interrupted State",
+			interrupt: true,
+			seconds:   10,
+		},
+		{
+			name:      "Between States",
+			interrupt: false,
+			seconds:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &StepActivity{}
+			engine, err := config.Initialize(
+				config.WithDebug(true),
+				config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+				config.WithActivity("Step", step),
+				config.WithActivity("Slow", &SlowActivity{}),
+			)
+			require.NoError(t, err)
+
+			workflow, err := parser.NewParser(engine.GetRegistry()).ParseFromBytes([]byte(fmt.Sprintf(`{
+				"id": "compensation-timeout",
+				"specVersion": "0.8",
+				"start": "ReserveStock",
+				"functions": [
+					{ "name": "Step", "operation": "Step" },
+					{ "name": "Slow", "operation": "Slow" }
+				],
+				"timeouts": {
+					"workflowExecTimeout": { "duration": "PT1S", "interrupt": %t }
+				},
+				"states": [
+					{
+						"name": "ReserveStock",
+						"type": "operation",
+						"actions": [ { "functionRef": { "refName": "Step", "arguments": { "step": "reserve", "data": "${ .current }" } } } ],
+						"compensatedBy": "ReleaseStock",
+						"transition": "AwaitPayment"
+					},
+					{
+						"name": "AwaitPayment",
+						"type": "operation",
+						"actions": [ { "functionRef": { "refName": "Slow", "arguments": { "seconds": %d } } } ],
+						"transition": "ShipOrder"
+					},
+					{
+						"name": "ShipOrder",
+						"type": "operation",
+						"actions": [ { "functionRef": { "refName": "Step", "arguments": { "step": "ship", "data": "${ .current }" } } } ],
+						"end": true
+					},
+					{
+						"name": "ReleaseStock",
+						"type": "operation",
+						"actions": [ { "functionRef": { "refName": "Step", "arguments": { "step": "release", "data": "${ .current }" } } } ],
+						"usedForCompensation": true,
+						"end": true
+					}
+				]
+			}`, tt.interrupt, tt.seconds)))
+			require.NoError(t, err)
+
+			_, err = engine.Execute(context.Background(), workflow, map[string]interface{}{"orderId": "A-1"}, nil)
+
+			// The workflow timeout fails the run, which undoes the states completed before it
+			requireTimeout(t, err, "workflowExecTimeout")
+			require.Equal(t, []string{"reserve", "release"}, step.executed)
+		})
+	}
+}
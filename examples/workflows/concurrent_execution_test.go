@@ -0,0 +1,118 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// greetingWorkflow returns a workflow whose states share their names with every other greeting workflow,
+// so that resolving a state from the wrong definition shows up in the result
+func greetingWorkflow(t *testing.T, p *parser.Parser, id string, greeting string) *engine.ServerlessWorkflow {
+	workflow, err := p.ParseFromBytes([]byte(fmt.Sprintf(`{
+		"id": "%s",
+		"specVersion": "0.8",
+		"start": "Greet",
+		"functions": [{ "name": "JQ", "operation": "jq:transform" }],
+		"states": [
+			{
+				"name": "Greet",
+				"type": "operation",
+				"actions": [
+					{
+						"functionRef": {
+							"refName": "JQ",
+							"arguments": {
+								"query": "{ message: (\"%s, \" + .name), request: .request }",
+								"data": "${ .current }"
+							}
+						}
+					}
+				],
+				"transition": "Tag"
+			},
+			{
+				"name": "Tag",
+				"type": "inject",
+				"data": { "workflow": "%s", "region": "${ .globals.region }" },
+				"end": true
+			}
+		]
+	}`, id, greeting, id)))
+	require.NoError(t, err)
+	return workflow
+}
+
+func TestConcurrentExecutions(t *testing.T) {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.ErrorLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(e.GetRegistry())
+	workflows := map[string]*engine.ServerlessWorkflow{
+		"Hello": greetingWorkflow(t, p, "hello-workflow", "Hello"),
+		"Hola":  greetingWorkflow(t, p, "hola-workflow", "Hola"),
+	}
+
+	// Globals are shared by every execution, as they would be in a server
+	globals := map[string]interface{}{"region": "eu-west"}
+
+	const executionsPerWorkflow = 25
+	var wg sync.WaitGroup
+	for greeting, workflow := range workflows {
+		for i := 0; i < executionsPerWorkflow; i++ {
+			wg.Add(1)
+			go func(greeting string, workflow *engine.ServerlessWorkflow, request int) {
+				defer wg.Done()
+
+				input := map[string]interface{}{"name": "Jane", "request": request}
+				result, err := e.Execute(context.Background(), workflow, input, globals)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				assert.Equal(t, map[string]interface{}{
+					"message":  greeting + ", Jane",
+					"request":  request,
+					"workflow": workflow.ID,
+					"region":   "eu-west",
+				}, result.Data)
+
+				// Every execution only sees the debug data of its own states
+				if assert.Len(t, result.Debug.States, 2) {
+					assert.Equal(t, "Greet", result.Debug.States[0].Name)
+					assert.Equal(t, "Tag", result.Debug.States[1].Name)
+					assert.Len(t, result.Debug.States[0].Actions, 1)
+				}
+			}(greeting, workflow, i)
+		}
+	}
+	wg.Wait()
+}
+
+func TestSequentialExecutionsOfDifferentWorkflows(t *testing.T) {
+	e, err := config.Initialize(
+		config.WithLogger(zap.NewLogger(logger.ErrorLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(e.GetRegistry())
+	for _, greeting := range []string{"Hello", "Hola", "Bonjour"} {
+		workflow := greetingWorkflow(t, p, greeting+"-workflow", greeting)
+
+		result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"name": "Jane"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, greeting+", Jane", result.Data.(map[string]interface{})["message"])
+	}
+}
@@ -0,0 +1,103 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalActionsWorkflow(t *testing.T) {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(e.GetRegistry())
+	workflow, err := p.ParseFromFile("conditional_actions_workflow.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		expected map[string]interface{}
+		skipped  []bool
+		minTime  time.Duration
+	}{
+		{
+			name:  "All Actions",
+			input: map[string]interface{}{"email": "ada@example.com", "phone": "555-0100"},
+			expected: map[string]interface{}{
+				"email": "ada@example.com",
+				"phone": "555-0100",
+				"notifications": map[string]interface{}{
+					"email": "email sent to ada@example.com",
+					"sms":   "sms sent to 555-0100",
+				},
+			},
+			skipped: []bool{false, false},
+			// The SMS action sleeps before and after it runs
+			minTime: 2 * time.Second,
+		},
+		{
+			name:  "Skipped Action",
+			input: map[string]interface{}{"email": "ada@example.com"},
+			expected: map[string]interface{}{
+				"email": "ada@example.com",
+				"notifications": map[string]interface{}{
+					"email": "email sent to ada@example.com",
+				},
+			},
+			skipped: []bool{false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := e.Execute(context.Background(), workflow, tt.input, nil)
+			require.NoError(t, err)
+
+			writeToFile("outputs/conditional_actions_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+			writeToFile("outputs/conditional_actions_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			require.Equal(t, tt.expected, result.Data)
+			require.GreaterOrEqual(t, result.Duration, tt.minTime)
+			if tt.minTime == 0 {
+				// Skipped actions do not sleep
+				require.Less(t, result.Duration, time.Second)
+			}
+
+			// Skipped actions are recorded without running the activity
+			actions := result.Debug.States[0].Actions
+			require.Len(t, actions, len(tt.skipped))
+			for i, skipped := range tt.skipped {
+				require.Equal(t, "JQ", actions[i].ActivityName)
+				require.Equal(t, skipped, actions[i].Skipped)
+				if skipped {
+					require.Nil(t, actions[i].Output)
+				}
+			}
+		})
+	}
+
+	t.Run("Invalid Condition", func(t *testing.T) {
+		_, err := p.ParseFromJSON([]byte(`{
+			"id": "broken", "version": "1.0", "specVersion": "0.8", "start": "Notify",
+			"states": [{
+				"name": "Notify", "type": "operation", "end": true,
+				"actions": [{
+					"condition": "${ .current.phone != }",
+					"functionRef": { "refName": "JQ", "arguments": { "query": ".", "data": "${ .current }" } }
+				}]
+			}]
+		}`))
+		require.ErrorContains(t, err, "state 'Notify' has an invalid JQ expression '${ .current.phone != }'")
+	})
+}
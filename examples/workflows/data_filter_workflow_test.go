@@ -0,0 +1,61 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataFilterWorkflow(t *testing.T) {
+	engine, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(engine.GetRegistry())
+	workflow, err := p.ParseFromFile("data_filter_workflow.json")
+	require.NoError(t, err)
+
+	input := map[string]interface{}{
+		"requestId": "req-1",
+		"order": map[string]interface{}{
+			"customer": "Jane",
+			"items": []interface{}{
+				map[string]interface{}{"sku": "A", "price": 10.0},
+				map[string]interface{}{"sku": "B", "price": 20.0},
+			},
+		},
+	}
+
+	result, err := engine.Execute(context.Background(), workflow, input, nil)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	writeToFile("outputs/data_filter_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+	writeToFile("outputs/data_filter_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+	// Results are merged into .pricing, the audit result is discarded and the output filter trims the state data
+	require.Equal(t, map[string]interface{}{
+		"customer": "Jane",
+		"pricing": map[string]interface{}{
+			"total":    30.0,
+			"count":    2.0,
+			"discount": 5.0,
+		},
+	}, result.Data)
+
+	// fromStateData narrows what the action's arguments are resolved against
+	actions := result.Debug.States[0].Actions
+	require.Len(t, actions, 3)
+	require.Equal(t, input["order"].(map[string]interface{})["items"], actions[0].Arguments.(map[string]any)["data"])
+
+	// The raw action result is still visible in the debug data
+	require.Equal(t, map[string]interface{}{"audited": true}, actions[1].Output)
+}
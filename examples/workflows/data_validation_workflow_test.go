@@ -0,0 +1,132 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataValidationWorkflow(t *testing.T) {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(e.GetRegistry())
+	workflow, err := p.ParseFromFile("data_validation_workflow.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		input      map[string]interface{}
+		expected   interface{}
+		state      string
+		violations []string
+	}{
+		{
+			name:     "Valid Order",
+			input:    map[string]interface{}{"orderId": "A-1", "price": 12.5, "quantity": 2, "discount": 5},
+			expected: map[string]interface{}{"orderId": "A-1", "total": float64(20)},
+		},
+		{
+			name:  "Invalid Input",
+			input: map[string]interface{}{"orderId": "", "price": "12.5"},
+			violations: []string{
+				"/: missing properties: 'quantity'",
+				"/orderId: length must be >= 1, but got 0",
+				"/price: expected number, but got string",
+			},
+		},
+		{
+			name:       "Invalid State Output",
+			input:      map[string]interface{}{"orderId": "A-2", "price": 1, "quantity": 1, "discount": 3},
+			state:      "PriceOrder",
+			violations: []string{"/total: must be >= 0 but found -2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := e.Execute(context.Background(), workflow, tt.input, nil)
+			if tt.violations == nil {
+				require.NoError(t, err)
+
+				writeToFile("outputs/data_validation_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+				writeToFile("outputs/data_validation_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+				require.Equal(t, tt.expected, result.Data)
+				return
+			}
+
+			validationErr := dataValidationError(t, err)
+			require.Equal(t, tt.state, validationErr.Context.StateName)
+			require.ElementsMatch(t, tt.violations, validationErr.Context.AdditionalInfo["violations"])
+		})
+	}
+
+	t.Run("Ignored Validation Errors", func(t *testing.T) {
+		lenient, err := p.ParseFromFile("data_validation_workflow.json")
+		require.NoError(t, err)
+		failOnValidationErrors := false
+		lenient.DataInputSchema.FailOnValidationErrors = &failOnValidationErrors
+
+		// A fractional quantity does not match the input schema, but the order can still be priced
+		result, err := e.Execute(context.Background(), lenient, map[string]interface{}{"orderId": "A-3", "price": 3, "quantity": 2.5}, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"orderId": "A-3", "total": 7.5}, result.Data)
+
+		// State output schemas are still enforced
+		_, err = e.Execute(context.Background(), lenient, map[string]interface{}{"price": 3, "quantity": 2}, nil)
+		validationErr := dataValidationError(t, err)
+		require.Equal(t, "PriceOrder", validationErr.Context.StateName)
+		require.Equal(t, []string{"/orderId: expected string, but got null"}, validationErr.Context.AdditionalInfo["violations"])
+	})
+
+	t.Run("Schemas Relative To Definition", func(t *testing.T) {
+		// The workflow and its schemas are moved to a directory the working directory has no schemas for
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "order_schemas"), 0o755))
+		for _, name := range []string{"data_validation_workflow.json", "schemas/order_input.json", "schemas/order_total.json"} {
+			content, err := os.ReadFile(name)
+			require.NoError(t, err)
+			content = []byte(strings.ReplaceAll(string(content), "schemas/", "order_schemas/"))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, strings.ReplaceAll(name, "schemas/", "order_schemas/")), content, 0o644))
+		}
+
+		moved, err := p.ParseFromFile(filepath.Join(dir, "data_validation_workflow.json"))
+		require.NoError(t, err)
+
+		result, err := e.Execute(context.Background(), moved, map[string]interface{}{"orderId": "A-4", "price": 2, "quantity": 3}, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"orderId": "A-4", "total": float64(6)}, result.Data)
+
+		_, err = e.Execute(context.Background(), moved, map[string]interface{}{"orderId": "A-4", "price": 2}, nil)
+		validationErr := dataValidationError(t, err)
+		require.Equal(t, []string{"/: missing properties: 'quantity'"}, validationErr.Context.AdditionalInfo["violations"])
+	})
+}
+
+// dataValidationError returns the ErrDataValidation error in the error chain
+func dataValidationError(t *testing.T, err error) *engine.WorkflowError {
+	require.Error(t, err)
+	var workflowErr *engine.WorkflowError
+	for cause := err; errors.As(cause, &workflowErr); cause = workflowErr.Cause {
+		if workflowErr.Code == engine.ErrDataValidation {
+			return workflowErr
+		}
+	}
+	t.Fatalf("no %s error in: %v", engine.ErrDataValidation, err)
+	return nil
+}
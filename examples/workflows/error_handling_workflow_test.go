@@ -17,9 +17,11 @@ import (
 
 func TestErrorHandlingWorkflow(t *testing.T) {
 	// Initialize engine with debugging enabled
+	// The workflow's onErrors reference parts of the error messages rather than declared errors
 	engine, err := config.Initialize(
 		config.WithDebug(true),
 		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithSubstringErrorMatching(),
 	)
 	require.NoError(t, err)
 
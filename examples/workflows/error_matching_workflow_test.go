@@ -0,0 +1,83 @@
+package workflows
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorMatchingWorkflow(t *testing.T) {
+	// The server responds with the status code given in the path
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, err := strconv.Atoi(r.URL.Path[1:])
+		if err != nil {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		substring bool
+		handledBy string
+	}{
+		{name: "HTTP Status Code", path: "/404", handledBy: "CreateProfile"},
+		{name: "JQ Predicate", path: "/503", handledBy: "UseCachedProfile"},
+		{name: "First Of Error Refs", path: "/401", handledBy: "DenyAccess"},
+		{name: "Second Of Error Refs", path: "/403", handledBy: "DenyAccess"},
+		{name: "Activity Error Code", method: "TEAPOT", path: "/200", handledBy: "RejectRequest"},
+		// Without substring matching the message of the error is not inspected
+		{name: "Message Ignored", path: "/418", handledBy: "HandleUnknownError"},
+		{name: "Substring Fallback", path: "/418", substring: true, handledBy: "HandleTeapot"},
+		// Declared errors still take precedence when substring matching is enabled
+		{name: "Substring Fallback With Declared Error", path: "/404", substring: true, handledBy: "CreateProfile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []config.Option{
+				config.WithDebug(true),
+				config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+			}
+			if tt.substring {
+				opts = append(opts, config.WithSubstringErrorMatching())
+			}
+			engine, err := config.Initialize(opts...)
+			require.NoError(t, err)
+
+			workflow, err := parser.NewParser(engine.GetRegistry()).ParseFromFile("error_matching_workflow.json")
+			require.NoError(t, err)
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			input := map[string]interface{}{
+				"url":    server.URL + tt.path,
+				"method": method,
+			}
+
+			result, err := engine.Execute(context.Background(), workflow, input, nil)
+			require.NoError(t, err)
+
+			writeToFile("outputs/error_matching_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+			writeToFile("outputs/error_matching_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			require.Equal(t, tt.handledBy, result.Data.(map[string]interface{})["handledBy"])
+			require.Equal(t, tt.handledBy, result.Debug.States[1].Name)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorRoutingWorkflow(t *testing.T) {
+	engine, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(engine.GetRegistry())
+	workflow, err := p.ParseFromFile("error_routing_workflow.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		states   []string
+		validate func(t *testing.T, data map[string]interface{})
+	}{
+		{
+			name:   "Fee Charged",
+			input:  map[string]interface{}{"score": "700", "amount": "250"},
+			states: []string{"CheckScore", "ChargeFee"},
+			validate: func(t *testing.T, data map[string]interface{}) {
+				require.Equal(t, map[string]interface{}{"fee": 2.5}, data)
+			},
+		},
+		{
+			name:   "Invalid Score",
+			input:  map[string]interface{}{"score": "high", "amount": "250"},
+			states: []string{"CheckScore", "HandleInvalidScore"},
+			validate: func(t *testing.T, data map[string]interface{}) {
+				require.Equal(t, map[string]interface{}{
+					"code":       "EXPRESSION_EVAL_FAILED",
+					"state":      "CheckScore",
+					"expression": ".current.score | tonumber >= 600",
+				}, data["failure"])
+			},
+		},
+		{
+			name:   "Invalid Amount",
+			input:  map[string]interface{}{"score": "700", "amount": "n/a"},
+			states: []string{"CheckScore", "ChargeFee", "HandleInvalidAmount"},
+			validate: func(t *testing.T, data map[string]interface{}) {
+				failure := data["failure"].(map[string]interface{})
+				require.Equal(t, "EXPRESSION_EVAL_FAILED", failure["code"])
+				require.Equal(t, "JQ", failure["activity"])
+				require.Contains(t, failure["cause"], "tonumber")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.Execute(context.Background(), workflow, tt.input, nil)
+			require.NoError(t, err)
+
+			writeToFile("outputs/error_routing_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+			writeToFile("outputs/error_routing_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			var states []string
+			for _, state := range result.Debug.States {
+				states = append(states, state.Name)
+			}
+			require.Equal(t, tt.states, states)
+			tt.validate(t, result.Data.(map[string]interface{}))
+		})
+	}
+
+	t.Run("Invalid Output", func(t *testing.T) {
+		workflow, err := p.ParseFromJSON([]byte(`{
+			"id": "invalid-output", "version": "1.0", "specVersion": "0.8", "start": "Summarize",
+			"states": [
+				{
+					"name": "Summarize", "type": "inject", "end": true,
+					"data": { "total": "n/a" },
+					"stateDataFilter": { "output": "${ { total: (.total | tonumber) } }" },
+					"onErrors": [{ "errorRef": "DATA_TRANSFORM_FAILED", "transition": "HandleInvalidOutput" }]
+				},
+				{
+					"name": "HandleInvalidOutput", "type": "inject", "end": true,
+					"data": { "failure": { "code": "${ .error.code }", "state": "${ .error.state }" } }
+				}
+			]
+		}`))
+		require.NoError(t, err)
+
+		result, err := engine.Execute(context.Background(), workflow, map[string]interface{}{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{
+			"code":  "DATA_TRANSFORM_FAILED",
+			"state": "Summarize",
+		}, result.Data.(map[string]interface{})["failure"])
+	})
+}
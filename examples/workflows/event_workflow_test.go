@@ -0,0 +1,300 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/events"
+	"github.com/kshitiz1403/jsonjuggler/events/channel"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func newEventEngine(t *testing.T, bus events.Bus) *engine.Engine {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithEventBus(bus),
+	)
+	require.NoError(t, err)
+	return e
+}
+
+// orderEvent returns an event of the given type correlated to the order
+func orderEvent(source, eventType, orderID string, data interface{}) events.Event {
+	return events.Event{
+		ID:         eventType + "-" + orderID,
+		Source:     source,
+		Type:       eventType,
+		Extensions: map[string]string{"orderid": orderID},
+		Data:       data,
+	}
+}
+
+func TestEventWorkflow(t *testing.T) {
+	payment := orderEvent("shop/payments", "com.shop.payment.received", "A-1", map[string]interface{}{"amount": 25.0})
+	otherPayment := orderEvent("shop/payments", "com.shop.payment.received", "B-2", map[string]interface{}{"amount": 99.0})
+	cancellation := orderEvent("shop/payments", "com.shop.payment.cancelled", "A-1", nil)
+	shipment := orderEvent("shop/warehouse", "com.shop.shipment.dispatched", "A-1", map[string]interface{}{"carrier": "DHL"})
+	invoice := orderEvent("shop/billing", "com.shop.invoice.issued", "A-1", map[string]interface{}{"number": "INV-7"})
+
+	tests := []struct {
+		name      string
+		published []events.Event
+		// delayed events are published while the workflow is waiting
+		delayed          []events.Event
+		expected         map[string]interface{}
+		matchedCondition string
+		consumed         []string
+	}{
+		{
+			name:      "Paid And Fulfilled",
+			published: []events.Event{otherPayment, payment},
+			delayed:   []events.Event{invoice, shipment},
+			expected: map[string]interface{}{
+				"orderId": "A-1",
+				"payment": map[string]interface{}{"amount": 25.0},
+				"shipment": map[string]interface{}{
+					"carrier":      "DHL",
+					"dispatchedBy": "shop/warehouse",
+				},
+				"invoice": map[string]interface{}{"number": "INV-7"},
+				"status":  "fulfilled",
+			},
+			matchedCondition: "paid",
+			consumed:         []string{payment.ID},
+		},
+		{
+			name:      "Payment Cancelled",
+			published: []events.Event{cancellation},
+			expected: map[string]interface{}{
+				"orderId": "A-1",
+				"status":  "cancelled",
+			},
+			matchedCondition: "cancelled",
+			consumed:         []string{cancellation.ID},
+		},
+		{
+			name:      "Payment Of Another Order Only",
+			published: []events.Event{otherPayment},
+			expected: map[string]interface{}{
+				"orderId": "A-1",
+				"status":  "expired",
+			},
+			matchedCondition: "default",
+		},
+		{
+			name:      "Invoice Never Issued",
+			published: []events.Event{payment, shipment},
+			// The state is not exclusive, so the shipment is only merged once the invoice arrived as well
+			expected: map[string]interface{}{
+				"orderId": "A-1",
+				"payment": map[string]interface{}{"amount": 25.0},
+				"status":  "fulfilment-overdue",
+			},
+			matchedCondition: "paid",
+			consumed:         []string{payment.ID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := channel.New()
+			defer bus.Close()
+			e := newEventEngine(t, bus)
+
+			workflow, err := parser.NewParser(e.GetRegistry()).ParseFromFile("event_workflow.json")
+			require.NoError(t, err)
+
+			for _, event := range tt.published {
+				require.NoError(t, bus.Publish(context.Background(), event))
+			}
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				for _, event := range tt.delayed {
+					bus.Publish(context.Background(), event)
+				}
+			}()
+
+			result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"orderId": "A-1"}, nil)
+			require.NoError(t, err)
+
+			writeToFile("outputs/event_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+			writeToFile("outputs/event_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			require.Equal(t, tt.expected, result.Data)
+
+			awaitPayment := result.Debug.States[0]
+			require.Equal(t, "AwaitPayment", awaitPayment.Name)
+			require.Equal(t, tt.matchedCondition, awaitPayment.MatchedCondition)
+			var consumed []string
+			for _, event := range awaitPayment.Events {
+				consumed = append(consumed, event.ID)
+			}
+			require.Equal(t, tt.consumed, consumed)
+		})
+	}
+}
+
+func TestExclusiveEventState(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    events.Event
+		expected map[string]interface{}
+	}{
+		{
+			name: "Approved",
+			event: events.Event{
+				Source: "reviews",
+				Type:   "com.reviews.approved",
+				Data:   map[string]interface{}{"reviewer": "jane"},
+			},
+			expected: map[string]interface{}{
+				"request":  "R-1",
+				"review":   map[string]interface{}{"reviewer": "jane"},
+				"decision": "approved",
+			},
+		},
+		{
+			name: "Rejected",
+			event: events.Event{
+				Source: "reviews",
+				Type:   "com.reviews.rejected",
+				Data:   map[string]interface{}{"reviewer": "john"},
+			},
+			// useData is false, so the event payload is not merged
+			expected: map[string]interface{}{
+				"request":  "R-1",
+				"decision": "rejected",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := channel.New()
+			defer bus.Close()
+			e := newEventEngine(t, bus)
+
+			workflow, err := parser.NewParser(e.GetRegistry()).ParseFromFile("event_exclusive_workflow.json")
+			require.NoError(t, err)
+
+			require.NoError(t, bus.Publish(context.Background(), tt.event))
+
+			result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"request": "R-1"}, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result.Data)
+
+			state := result.Debug.States[0]
+			require.Len(t, state.Events, 1)
+			require.Len(t, state.Actions, 1)
+		})
+	}
+}
+
+func TestEventWorkflowWithoutBus(t *testing.T) {
+	e, err := config.Initialize(config.WithLogger(zap.NewLogger(logger.DebugLevel)))
+	require.NoError(t, err)
+
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromFile("event_exclusive_workflow.json")
+	require.NoError(t, err)
+
+	_, err = e.Execute(context.Background(), workflow, map[string]interface{}{}, nil)
+	require.Error(t, err)
+	require.ErrorContains(t, err, string(engine.ErrEventBus))
+}
+
+func TestUncorrelatedEventsStayBuffered(t *testing.T) {
+	bus := channel.New()
+	defer bus.Close()
+	e := newEventEngine(t, bus)
+
+	// The order is correlated by the first event consumed, the orderid correlation declares no value
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromBytes([]byte(`{
+		"id": "fulfilment", "specVersion": "0.8", "start": "Fulfil",
+		"events": [
+			{ "name": "Shipped", "source": "shop/warehouse", "type": "com.shop.shipment.dispatched", "correlation": [{ "contextAttributeName": "orderid" }] },
+			{ "name": "Invoiced", "source": "shop/billing", "type": "com.shop.invoice.issued", "correlation": [{ "contextAttributeName": "orderid" }] }
+		],
+		"states": [{
+			"name": "Fulfil", "type": "event", "exclusive": false, "end": true,
+			"timeouts": { "eventTimeout": "PT2S" },
+			"onEvents": [{ "eventRefs": ["Shipped", "Invoiced"], "actions": [] }]
+		}]
+	}`))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, bus.Publish(ctx, orderEvent("shop/warehouse", "com.shop.shipment.dispatched", "A-1", nil)))
+	require.NoError(t, bus.Publish(ctx, orderEvent("shop/billing", "com.shop.invoice.issued", "B-2", nil)))
+	require.NoError(t, bus.Publish(ctx, orderEvent("shop/billing", "com.shop.invoice.issued", "A-1", nil)))
+
+	consumed := func(result *engine.ExecutionResult) []string {
+		var ids []string
+		for _, event := range result.Debug.States[0].Events {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+
+	result, err := e.Execute(ctx, workflow, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"com.shop.shipment.dispatched-A-1", "com.shop.invoice.issued-A-1"}, consumed(result))
+
+	// The invoice of the other order was not consumed by the first run and is still there for the second
+	require.NoError(t, bus.Publish(ctx, orderEvent("shop/warehouse", "com.shop.shipment.dispatched", "B-2", nil)))
+	result, err = e.Execute(ctx, workflow, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"com.shop.shipment.dispatched-B-2", "com.shop.invoice.issued-B-2"}, consumed(result))
+}
+
+func TestEventDataFilterUseData(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		expected map[string]interface{}
+	}{
+		{
+			// Without an eventDataFilter the payload is merged into the state data
+			name:     "Default",
+			expected: map[string]interface{}{"request": "R-1", "carrier": "DHL"},
+		},
+		{
+			name:     "Discarded",
+			filter:   `, "eventDataFilter": { "useData": false }`,
+			expected: map[string]interface{}{"request": "R-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := channel.New()
+			defer bus.Close()
+			e := newEventEngine(t, bus)
+
+			workflow, err := parser.NewParser(e.GetRegistry()).ParseFromBytes([]byte(`{
+				"id": "shipping", "specVersion": "0.8", "start": "Ship",
+				"events": [{ "name": "Shipped", "source": "shop/warehouse", "type": "com.shop.shipment.dispatched" }],
+				"states": [{
+					"name": "Ship", "type": "event", "end": true,
+					"timeouts": { "eventTimeout": "PT2S" },
+					"onEvents": [{ "eventRefs": ["Shipped"], "actions": []` + tt.filter + ` }]
+				}]
+			}`))
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			require.NoError(t, bus.Publish(ctx, orderEvent("shop/warehouse", "com.shop.shipment.dispatched", "A-1", map[string]interface{}{"carrier": "DHL"})))
+
+			result, err := e.Execute(ctx, workflow, map[string]interface{}{"request": "R-1"}, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result.Data)
+		})
+	}
+}
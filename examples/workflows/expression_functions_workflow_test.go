@@ -0,0 +1,94 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpressionFunctionsWorkflow(t *testing.T) {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(e.GetRegistry())
+	workflow, err := p.ParseFromFile("expression_functions_workflow.json")
+	require.NoError(t, err)
+
+	items := []interface{}{
+		map[string]interface{}{"price": 10, "quantity": 2},
+		map[string]interface{}{"price": 5, "quantity": 4},
+	}
+
+	tests := []struct {
+		name   string
+		input  map[string]interface{}
+		states []string
+		key    string
+		want   interface{}
+	}{
+		{
+			name:   "Regular Price",
+			input:  map[string]interface{}{"customer": map[string]interface{}{"age": 30}, "items": items},
+			states: []string{"CheckAge", "PriceOrder"},
+			key:    "total",
+			want:   float64(40),
+		},
+		{
+			name: "Coupon Price",
+			input: map[string]interface{}{
+				"customer": map[string]interface{}{"age": 30},
+				"items":    items,
+				"coupon":   map[string]interface{}{"discount": 0.25},
+			},
+			states: []string{"CheckAge", "PriceOrder"},
+			key:    "total",
+			want:   float64(30),
+		},
+		{
+			name:   "Rejected",
+			input:  map[string]interface{}{"customer": map[string]interface{}{"age": 16}, "items": items},
+			states: []string{"CheckAge", "Reject"},
+			key:    "rejected",
+			want:   "customer is not an adult",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := e.Execute(context.Background(), workflow, tt.input, nil)
+			require.NoError(t, err)
+
+			writeToFile("outputs/expression_functions_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+			writeToFile("outputs/expression_functions_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			states := make([]string, len(result.Debug.States))
+			for i, state := range result.Debug.States {
+				states[i] = state.Name
+			}
+			require.Equal(t, tt.states, states)
+			require.Equal(t, tt.want, result.Data.(map[string]interface{})[tt.key])
+		})
+	}
+
+	t.Run("Undeclared Expression Function", func(t *testing.T) {
+		_, err := p.ParseFromJSON([]byte(`{
+			"id": "broken", "version": "1.0", "specVersion": "0.8", "start": "Check",
+			"functions": [{ "name": "isAdult", "operation": ".customer.age >= 18", "type": "expression" }],
+			"states": [{
+				"name": "Check", "type": "switch",
+				"dataConditions": [{ "condition": "${ fn:isMinor }", "end": true }],
+				"defaultCondition": { "end": true }
+			}]
+		}`))
+		require.ErrorContains(t, err, "state 'Check' has an invalid JQ expression '${ fn:isMinor }': expression function 'isMinor' is not declared")
+	})
+}
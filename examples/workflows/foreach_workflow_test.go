@@ -0,0 +1,182 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// ConcurrencyTracker records how many iterations run at the same time
+type ConcurrencyTracker struct {
+	activities.BaseActivity
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+// Track simulates work and fails for the element "fail"
+func (a *ConcurrencyTracker) Track(ctx context.Context, args map[string]any) (interface{}, error) {
+	a.mu.Lock()
+	a.running++
+	if a.running > a.peak {
+		a.peak = a.running
+	}
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.running--
+		a.mu.Unlock()
+	}()
+
+	if args["value"] == "fail" {
+		return nil, errors.New("iteration failed")
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(50 * time.Millisecond):
+	}
+	return args["value"], nil
+}
+
+func TestForEachWorkflow(t *testing.T) {
+	engine, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(engine.GetRegistry())
+	workflow, err := p.ParseFromFile("foreach_workflow.json")
+	require.NoError(t, err)
+
+	input := map[string]interface{}{
+		"order": map[string]interface{}{
+			"id": "ORD123",
+			"lines": []interface{}{
+				map[string]interface{}{"sku": "A", "price": 10, "quantity": 2},
+				map[string]interface{}{"sku": "B", "price": 5, "quantity": 1},
+				map[string]interface{}{"sku": "C", "price": 1, "quantity": 3},
+			},
+		},
+	}
+
+	result, err := engine.Execute(context.Background(), workflow, input, nil)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	writeToFile("outputs/foreach_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+	writeToFile("outputs/foreach_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+	require.Equal(t, map[string]interface{}{
+		"id": "ORD123",
+		"lines": []interface{}{
+			map[string]interface{}{"sku": "A", "total": float64(20)},
+			map[string]interface{}{"sku": "B", "total": float64(5)},
+			map[string]interface{}{"sku": "C", "total": float64(3)},
+		},
+		"total": float64(28),
+	}, result.Data)
+
+	// Every iteration is recorded with its own actions
+	iterations := result.Debug.States[0].Iterations
+	require.Len(t, iterations, 3)
+	for i, iteration := range iterations {
+		require.Equal(t, input["order"].(map[string]interface{})["lines"].([]interface{})[i], iteration.Input)
+		require.Len(t, iteration.Actions, 1)
+	}
+}
+
+func TestForEachWorkflowModes(t *testing.T) {
+	definition := func(mode string, batchSize string) []byte {
+		return []byte(`{
+			"id": "foreach-modes",
+			"specVersion": "0.8",
+			"start": "Iterate",
+			"functions": [{ "name": "Track", "operation": "Track" }],
+			"states": [
+				{
+					"name": "Iterate",
+					"type": "foreach",
+					"inputCollection": "${ .current.values }",
+					"outputCollection": "${ .results }",
+					"mode": "` + mode + `",
+					"batchSize": ` + batchSize + `,
+					"actions": [
+						{ "functionRef": { "refName": "Track", "arguments": { "value": "${ .item }" } } }
+					],
+					"end": true
+				}
+			]
+		}`)
+	}
+
+	tests := []struct {
+		name        string
+		mode        string
+		batchSize   string
+		values      []interface{}
+		maxPeak     int
+		expectError bool
+	}{
+		{
+			name:      "Sequential",
+			mode:      "sequential",
+			batchSize: "1",
+			values:    []interface{}{"a", "b", "c"},
+			maxPeak:   1,
+		},
+		{
+			name:      "Parallel With Batch Size",
+			mode:      "parallel",
+			batchSize: "2",
+			values:    []interface{}{"a", "b", "c", "d", "e"},
+			maxPeak:   2,
+		},
+		{
+			name:        "Failing Iteration",
+			mode:        "parallel",
+			batchSize:   "5",
+			values:      []interface{}{"a", "fail", "c"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := &ConcurrencyTracker{}
+			engine, err := config.Initialize(
+				config.WithDebug(true),
+				config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+				config.WithActivityStruct(tracker),
+			)
+			require.NoError(t, err)
+
+			workflow, err := parser.NewParser(engine.GetRegistry()).ParseFromBytes(definition(tt.mode, tt.batchSize))
+			require.NoError(t, err)
+
+			result, err := engine.Execute(context.Background(), workflow, map[string]interface{}{"values": tt.values}, nil)
+			if tt.expectError {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "iteration 1 failed")
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.values, result.Data.(map[string]interface{})["results"])
+			require.LessOrEqual(t, tracker.peak, tt.maxPeak)
+			require.Len(t, result.Debug.States[0].Iterations, len(tt.values))
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// QuoteActivity quotes the shipping price of a parcel by weight
+type QuoteActivity struct {
+	activities.BaseActivity
+}
+
+func (a *QuoteActivity) Execute(ctx context.Context, args map[string]any) (interface{}, error) {
+	weight, ok := args["weight"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("weight must be a number, got %T", args["weight"])
+	}
+	return map[string]interface{}{"carrier": "ground", "price": 2 * weight}, nil
+}
+
+func TestFunctionsWorkflow(t *testing.T) {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("QuoteShipping", &QuoteActivity{}),
+		config.WithOperation("shipping:quote", "QuoteShipping"),
+	)
+	require.NoError(t, err)
+
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromFile("functions_workflow.json")
+	require.NoError(t, err)
+
+	input := map[string]interface{}{
+		"weight": 3.0,
+		"items": []interface{}{
+			map[string]interface{}{"price": 10, "quantity": 2},
+			map[string]interface{}{"price": 5, "quantity": 1},
+		},
+	}
+	result, err := e.Execute(context.Background(), workflow, input, nil)
+	require.NoError(t, err)
+
+	writeToFile("outputs/functions_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+	writeToFile("outputs/functions_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+	data := result.Data.(map[string]interface{})
+	require.EqualValues(t, 25, data["total"])
+	require.Equal(t, map[string]interface{}{"carrier": "ground", "price": float64(6)}, data["shipping"])
+
+	// Actions are reported under the function they call
+	actions := result.Debug.States[0].Actions
+	require.Len(t, actions, 2)
+	require.Equal(t, "Total", actions[0].ActivityName)
+	require.Equal(t, "Quote", actions[1].ActivityName)
+}
+
+// carrierAPI is the OpenAPI document of the carrier service the rest functions call. The server URL is filled in
+// with the address of the test server.
+const carrierAPI = `openapi: 3.0.3
+info:
+  title: Carrier API
+  version: "1.0"
+servers:
+  - url: %s/v1
+paths:
+  /carriers/{carrierId}/quotes:
+    parameters:
+      - name: carrierId
+        in: path
+        required: true
+    get:
+      operationId: getQuote
+      parameters:
+        - name: weight
+          in: query
+          required: true
+        - $ref: "#/components/parameters/RequestID"
+  /shipments:
+    post:
+      operationId: createShipment
+      requestBody:
+        content:
+          application/json: {}
+components:
+  parameters:
+    RequestID:
+      name: X-Request-ID
+      in: header
+`
+
+func TestRESTFunctions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && (r.URL.Path == "/v1/carriers/ground express/quotes" || r.URL.Path == "/v1/carriers/1234567/quotes"):
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"price":     12.5,
+				"weight":    r.URL.Query().Get("weight"),
+				"requestId": r.Header.Get("X-Request-ID"),
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/shipments":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"shipment": body})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	document := filepath.Join(t.TempDir(), "carrier.yaml")
+	require.NoError(t, os.WriteFile(document, []byte(fmt.Sprintf(carrierAPI, server.URL)), 0644))
+
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+	)
+	require.NoError(t, err)
+	p := parser.NewParser(e.GetRegistry())
+
+	workflow, err := p.ParseFromJSON([]byte(`{
+		"id": "ship-order", "version": "1.0", "specVersion": "0.8", "start": "Ship",
+		"functions": [
+			{ "name": "GetQuote", "operation": "` + document + `#getQuote" },
+			{ "name": "CreateShipment", "operation": "` + document + `#createShipment", "type": "rest" }
+		],
+		"states": [{
+			"name": "Ship", "type": "operation", "end": true,
+			"actions": [
+				{
+					"functionRef": {
+						"refName": "GetQuote",
+						"arguments": { "carrierId": "ground express", "weight": "${ .current.weight }", "X-Request-ID": "req-1" }
+					},
+					"actionDataFilter": { "results": "${ .body }", "toStateData": "${ .quote }" }
+				},
+				{
+					"functionRef": {
+						"refName": "CreateShipment",
+						"arguments": { "orderId": "${ .current.orderId }", "price": "${ .current.quote.price }" }
+					},
+					"actionDataFilter": { "results": "${ .body.shipment }", "toStateData": "${ .shipment }" }
+				}
+			]
+		}]
+	}`))
+	require.NoError(t, err)
+
+	result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"orderId": "A-1", "weight": 12}, nil)
+	require.NoError(t, err)
+
+	writeToFile("outputs/rest_functions_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+	writeToFile("outputs/rest_functions_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+	require.Equal(t, map[string]interface{}{
+		"orderId":  "A-1",
+		"weight":   12,
+		"quote":    map[string]interface{}{"price": 12.5, "weight": "12", "requestId": "req-1"},
+		"shipment": map[string]interface{}{"orderId": "A-1", "price": 12.5},
+	}, result.Data)
+
+	t.Run("Missing Parameter", func(t *testing.T) {
+		_, err := e.Execute(context.Background(), workflow, map[string]interface{}{"orderId": "A-1"}, nil)
+		require.ErrorContains(t, err, "missing required query parameter 'weight' of operation 'getQuote'")
+	})
+
+	t.Run("Numeric Parameters", func(t *testing.T) {
+		// Numbers decoded from JSON are float64, and fill parameters without an exponent
+		quote, err := p.ParseFromJSON([]byte(`{
+			"id": "quote-order", "version": "1.0", "specVersion": "0.8", "start": "Quote",
+			"functions": [{ "name": "GetQuote", "operation": "` + document + `#getQuote" }],
+			"states": [{
+				"name": "Quote", "type": "operation", "end": true,
+				"actions": [{
+					"functionRef": {
+						"refName": "GetQuote",
+						"arguments": { "carrierId": "${ .current.carrierId }", "weight": "${ .current.weight }" }
+					},
+					"actionDataFilter": { "results": "${ .body }" }
+				}]
+			}]
+		}`))
+		require.NoError(t, err)
+
+		var input map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(`{"carrierId": 1234567, "weight": 2500000}`), &input))
+		result, err := e.Execute(context.Background(), quote, input, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"price": 12.5, "weight": "2500000", "requestId": ""}, result.Data)
+	})
+
+	t.Run("Invalid Functions", func(t *testing.T) {
+		_, err := p.ParseFromJSON([]byte(`{
+			"id": "broken", "version": "1.0", "specVersion": "0.8", "start": "Ship",
+			"functions": [
+				{ "name": "Cancel", "operation": "` + document + `", "type": "rest" },
+				{ "name": "Notify", "operation": "notify.proto#Notifier#Send", "type": "rpc" }
+			],
+			"states": [{
+				"name": "Ship", "type": "operation", "end": true,
+				"actions": [{ "functionRef": "Cancel" }, { "functionRef": "Deliver" }]
+			}]
+		}`))
+		var errs parser.ValidationErrors
+		require.ErrorAs(t, err, &errs)
+
+		messages := make([]string, len(errs))
+		for i, problem := range errs {
+			messages[i] = problem.Path + ": " + problem.Message
+		}
+		require.Equal(t, []string{
+			"functions[0].operation: rest function 'Cancel': operation '" + document + "' is not of the form <document>#<operationId>",
+			"functions[1].operation: function 'Notify' has type 'rpc', which is not supported",
+			"states[0].actions[1].functionRef.refName: state 'Ship' references function 'Deliver', which is not declared in functions",
+		}, messages)
+	})
+
+	t.Run("Resolved When Called", func(t *testing.T) {
+		// The OpenAPI document is loaded when a run first calls the function, not when the workflow is parsed
+		tracking, err := p.ParseFromJSON([]byte(`{
+			"id": "track-order", "version": "1.0", "specVersion": "0.8", "start": "Track",
+			"functions": [{ "name": "Track", "operation": "` + document + `#trackShipment" }],
+			"states": [{
+				"name": "Track", "type": "operation", "end": true,
+				"actions": [{ "functionRef": "Track" }]
+			}]
+		}`))
+		require.NoError(t, err)
+
+		_, err = e.Execute(context.Background(), tracking, map[string]interface{}{}, nil)
+		require.ErrorContains(t, err, "OpenAPI document '"+document+"' has no operation 'trackShipment'")
+	})
+}
@@ -0,0 +1,226 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadInterval = 10 * time.Millisecond
+
+// writeDefinition writes a workflow file with a modification time that always differs from the previous one
+func writeDefinition(t *testing.T, path string, definition string) {
+	require.NoError(t, os.WriteFile(path, []byte(definition), 0644))
+	modTime := time.Now().Add(time.Duration(len(definition)) * time.Second)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func greetingDefinition(version string, greeting string) string {
+	return `{
+		"id": "greeting", "version": "` + version + `", "specVersion": "0.8", "start": "Greet",
+		"states": [{ "name": "Greet", "type": "inject", "data": { "greeting": "` + greeting + `" }, "end": true }]
+	}`
+}
+
+func greet(t *testing.T, e *engine.Engine, version string) interface{} {
+	result, err := e.ExecuteByID(context.Background(), "greeting", version, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	return result.Data.(map[string]interface{})["greeting"]
+}
+
+func TestHotReloadWorkflows(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "greeting.json")
+
+	// The first definition waits for the gate before greeting
+	writeDefinition(t, path, `{
+		"id": "greeting", "version": "1.0", "specVersion": "0.8", "start": "Gate",
+		"functions": [{ "name": "Wait", "operation": "Wait" }],
+		"states": [
+			{
+				"name": "Gate", "type": "operation", "transition": "Greet",
+				"actions": [{ "functionRef": { "refName": "Wait", "arguments": { "step": "gate", "data": "${ .current }" } } }]
+			},
+			{ "name": "Greet", "type": "inject", "data": { "greeting": "Hello" }, "end": true }
+		]
+	}`)
+
+	wait := newWaitActivity("gate")
+	e, err := config.Initialize(
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("Wait", wait),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, e.WatchWorkflows(ctx, dir, reloadInterval))
+
+	inFlight := make(chan interface{}, 1)
+	go func() {
+		result, err := e.ExecuteByID(context.Background(), "greeting", "", map[string]interface{}{}, nil)
+		if err != nil {
+			inFlight <- err
+			return
+		}
+		inFlight <- result.Data.(map[string]interface{})["greeting"]
+	}()
+	require.Equal(t, "gate", receive(t, wait.started))
+	original, ok := e.GetWorkflowRegistry().Get("greeting", "1.0")
+	require.True(t, ok)
+
+	// New executions get the edited definition, the running execution keeps the one it started with
+	writeDefinition(t, path, greetingDefinition("1.0", "Hi"))
+	require.Eventually(t, func() bool {
+		reloaded, _ := e.GetWorkflowRegistry().Get("greeting", "1.0")
+		return reloaded != original
+	}, 5*time.Second, reloadInterval)
+	require.Equal(t, "Hi", greet(t, e, ""))
+
+	close(wait.release["gate"])
+	require.Equal(t, "Hello", receive(t, inFlight))
+
+	// Invalid edits are rejected and the last valid definition stays registered
+	invalid := []string{
+		`{ "id": "greeting", "version": "1.0", "states": [`,
+		`{
+			"id": "greeting", "version": "1.0", "specVersion": "0.8", "start": "Greet",
+			"functions": [{ "name": "SendSMS", "operation": "SendSMS" }],
+			"states": [{
+				"name": "Greet", "type": "operation", "end": true,
+				"actions": [{ "functionRef": { "refName": "SendSMS" } }]
+			}]
+		}`,
+	}
+	for _, definition := range invalid {
+		before, ok := e.GetWorkflowRegistry().Get("greeting", "1.0")
+		require.True(t, ok)
+
+		writeDefinition(t, path, definition)
+		time.Sleep(10 * reloadInterval)
+
+		after, ok := e.GetWorkflowRegistry().Get("greeting", "1.0")
+		require.True(t, ok)
+		require.Same(t, before, after)
+		require.Equal(t, "Hi", greet(t, e, ""))
+	}
+
+	// A new version in a new file becomes the latest version
+	writeDefinition(t, filepath.Join(dir, "greeting_v2.json"), greetingDefinition("2.0", "Welcome"))
+	require.Eventually(t, func() bool {
+		_, ok := e.GetWorkflowRegistry().Get("greeting", "2.0")
+		return ok
+	}, 5*time.Second, reloadInterval)
+	require.Equal(t, "Welcome", greet(t, e, ""))
+	require.Equal(t, "Hi", greet(t, e, "1.0"))
+
+	// Removing a file unregisters its definition
+	require.NoError(t, os.Remove(filepath.Join(dir, "greeting_v2.json")))
+	require.Eventually(t, func() bool {
+		_, ok := e.GetWorkflowRegistry().Get("greeting", "2.0")
+		return !ok
+	}, 5*time.Second, reloadInterval)
+	require.Equal(t, "Hi", greet(t, e, ""))
+}
+
+func TestHotReloadRecompilesSchemas(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "greeting.json")
+	// The schema is kept out of the watched directory, which only holds workflow files
+	schema := filepath.Join(t.TempDir(), "greeting_input.json")
+	require.NoError(t, os.WriteFile(schema, []byte(`{ "type": "object", "required": ["name"] }`), 0644))
+
+	definition := func(greeting string) string {
+		return `{
+			"id": "greeting", "version": "1.0", "specVersion": "0.8", "start": "Greet",
+			"dataInputSchema": { "schema": "` + schema + `", "failOnValidationErrors": true },
+			"states": [{ "name": "Greet", "type": "inject", "data": { "greeting": "` + greeting + `" }, "end": true }]
+		}`
+	}
+	writeDefinition(t, path, definition("Hello"))
+
+	e, err := config.Initialize(config.WithLogger(zap.NewLogger(logger.DebugLevel)))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, e.WatchWorkflows(ctx, dir, reloadInterval))
+
+	_, err = e.ExecuteByID(context.Background(), "greeting", "", map[string]interface{}{}, nil)
+	require.ErrorContains(t, err, string(engine.ErrDataValidation))
+
+	// The schema is edited along with the definition, and compiled again when the definition is reloaded
+	require.NoError(t, os.WriteFile(schema, []byte(`{ "type": "object" }`), 0644))
+	writeDefinition(t, path, definition("Hi"))
+	require.Eventually(t, func() bool {
+		result, err := e.ExecuteByID(context.Background(), "greeting", "", map[string]interface{}{}, nil)
+		return err == nil && result.Data.(map[string]interface{})["greeting"] == "Hi"
+	}, 5*time.Second, reloadInterval)
+}
+
+func TestHotReloadRejectsInvalidDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeDefinition(t, filepath.Join(dir, "greeting.json"), `{ "id": "greeting" `)
+
+	e, err := config.Initialize(config.WithLogger(zap.NewLogger(logger.DebugLevel)))
+	require.NoError(t, err)
+
+	err = e.WatchWorkflows(context.Background(), dir, reloadInterval)
+	require.ErrorContains(t, err, "greeting.json")
+	_, ok := e.GetWorkflowRegistry().Get("greeting", "")
+	require.False(t, ok)
+}
+
+func TestWatcherInitialSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "greeting.json")
+	writeDefinition(t, path, greetingDefinition("1.0", "Hello"))
+
+	e, err := config.Initialize(config.WithLogger(zap.NewLogger(logger.DebugLevel)))
+	require.NoError(t, err)
+	p := parser.NewParser(e.GetRegistry())
+
+	var changes []parser.Change
+	record := func(change parser.Change) error {
+		changes = append(changes, change)
+		return nil
+	}
+
+	// Definitions that fail to register are not part of the snapshot, the first poll reports them as added
+	watcher := p.NewWatcher(dir)
+	err = watcher.Load(func(workflows []*parser.Workflow) error {
+		return errors.New("rejected")
+	})
+	require.ErrorContains(t, err, "rejected")
+	require.NoError(t, watcher.Poll(record))
+	require.Len(t, changes, 1)
+	require.Nil(t, changes[0].Previous)
+	require.NotNil(t, changes[0].Workflow)
+
+	// Registered definitions make up the snapshot, so the first poll only reports the files edited since
+	var loaded []*parser.Workflow
+	watcher = p.NewWatcher(dir)
+	require.NoError(t, watcher.Load(func(workflows []*parser.Workflow) error {
+		loaded = workflows
+		return nil
+	}))
+	require.Len(t, loaded, 1)
+
+	changes = nil
+	require.NoError(t, watcher.Poll(record))
+	require.Empty(t, changes)
+
+	writeDefinition(t, path, greetingDefinition("1.0", "Hi"))
+	require.NoError(t, watcher.Poll(record))
+	require.Len(t, changes, 1)
+	require.Same(t, loaded[0], changes[0].Previous)
+}
@@ -0,0 +1,61 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectWorkflow(t *testing.T) {
+	engine, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(engine.GetRegistry())
+	workflow, err := p.ParseFromFile("inject_workflow.json")
+	require.NoError(t, err)
+
+	input := map[string]interface{}{
+		"customer": map[string]interface{}{
+			"name": "Jane",
+			"tier": "gold",
+		},
+		"currency": "EUR",
+	}
+	globals := map[string]interface{}{
+		"region": "eu-west",
+	}
+
+	result, err := engine.Execute(context.Background(), workflow, input, globals)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	writeToFile("outputs/inject_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+	writeToFile("outputs/inject_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+	// Injected data overrides existing keys and resolves templates
+	require.Equal(t, map[string]interface{}{
+		"customer": "Jane",
+		"currency": "USD",
+		"shipping": map[string]interface{}{
+			"method": "standard",
+			"region": "eu-west",
+		},
+		"tags": []interface{}{"new", "gold"},
+	}, result.Data)
+
+	// The inject state shows up in the debug trace like any other state
+	injectState := result.Debug.States[0]
+	require.Equal(t, "InjectDefaults", injectState.Name)
+	require.Equal(t, "inject", injectState.Type)
+	require.Equal(t, input, injectState.Input)
+	require.Equal(t, "USD", injectState.Output.(map[string]interface{})["currency"])
+}
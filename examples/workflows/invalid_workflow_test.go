@@ -0,0 +1,108 @@
+package workflows
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidWorkflow(t *testing.T) {
+	engine, err := config.Initialize(config.WithLogger(zap.NewLogger(logger.DebugLevel)))
+	require.NoError(t, err)
+
+	p := parser.NewParser(engine.GetRegistry())
+	_, err = p.ParseFromFile("invalid_workflow.json")
+	require.Error(t, err)
+
+	// Every problem is reported at once, with the state and the JSON path of the offending value
+	var errs parser.ValidationErrors
+	require.True(t, errors.As(err, &errs), err.Error())
+
+	type problem struct {
+		state   string
+		path    string
+		message string
+	}
+	expected := []problem{
+		{"Route", "states[0].defaultCondition", "switch state 'Route' has no default condition"},
+		{"Route", "states[0].dataConditions[0].condition", "state 'Route' has an invalid JQ expression '.current.total >'"},
+		{"Charge", "states[1].actions[0].functionRef.arguments.data", "state 'Charge' has an invalid JQ expression '${ .current.total | }'"},
+		{"Charge", "states[1].onErrors[0].transition", "state 'Charge' transitions to state 'Refund', which does not exist"},
+		{"WaitForPayment", "states[2].duration", "state 'WaitForPayment' has an invalid sleep duration 'PT1X'"},
+		{"Charge", "states[1].name", "state 'Charge' can never reach an end"},
+		{"WaitForPayment", "states[2].name", "state 'WaitForPayment' can never reach an end"},
+		{"CheckPayment", "states[3].name", "state 'CheckPayment' can never reach an end"},
+		{"Archive", "states[4].name", "state 'Archive' is not reachable from the start state"},
+	}
+	require.Len(t, errs, len(expected), err.Error())
+	for i, want := range expected {
+		require.Equal(t, want.state, errs[i].State)
+		require.Equal(t, want.path, errs[i].Path)
+		require.Contains(t, errs[i].Message, want.message)
+	}
+	require.ErrorContains(t, err, "9 problems in workflow definition")
+}
+
+func TestInvalidActivityArguments(t *testing.T) {
+	engine, err := config.Initialize(config.WithLogger(zap.NewLogger(logger.DebugLevel)))
+	require.NoError(t, err)
+	p := parser.NewParser(engine.GetRegistry())
+
+	definition := func(arguments string) string {
+		return `{
+			"id": "fetch", "version": "1.0", "specVersion": "0.8", "start": "Fetch",
+			"functions": [{ "name": "HTTPRequest", "operation": "http:request" }],
+			"states": [{
+				"name": "Fetch", "type": "operation", "end": true,
+				"actions": [{ "functionRef": { "refName": "HTTPRequest", "arguments": ` + arguments + ` } }]
+			}]
+		}`
+	}
+
+	tests := []struct {
+		name      string
+		arguments string
+		err       string
+	}{
+		{
+			name:      "Missing URL",
+			arguments: `{ "method": "GET" }`,
+			err:       "required argument 'url' is missing",
+		},
+		{
+			name:      "Invalid Method",
+			arguments: `{ "url": "https://example.com", "method": "FETCH" }`,
+			err:       "validation failed for field 'Method'",
+		},
+		{
+			name:      "Wrong Type",
+			arguments: `{ "url": "https://example.com", "method": "GET", "headers": "${ .current.headers }", "timeoutSec": "30" }`,
+			err:       "failed to decode arguments",
+		},
+		{
+			name:      "Templates",
+			arguments: `{ "url": "${ .current.url }", "method": "${ .current.method }", "headers": { "Authorization": "${ .current.token }" } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.ParseFromBytes([]byte(definition(tt.arguments)))
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.err)
+
+			var validationErr *parser.ValidationError
+			require.True(t, errors.As(err, &validationErr), err.Error())
+			require.Equal(t, "Fetch", validationErr.State)
+			require.Equal(t, "states[0].actions[0].functionRef.arguments", validationErr.Path)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package workflows
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/activities/jose"
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJOSEWorkflow(t *testing.T) {
+	encryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&encryptionKey.PublicKey)
+	require.NoError(t, err)
+	globals := map[string]interface{}{
+		"encryptionKey": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+		"decryptionKey": string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(encryptionKey)})),
+	}
+
+	// The signing key never appears in the workflow data, it is supplied by id
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithKeyProvider(jose.StaticKeys{"signing-key": signingKey}),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(e.GetRegistry())
+	workflow, err := p.ParseFromFile("jose_workflow.json")
+	require.NoError(t, err)
+
+	card := map[string]interface{}{"number": "4111111111111111", "expiry": "12/30"}
+	result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"orderId": "A-1", "card": card}, globals)
+	require.NoError(t, err)
+
+	writeToFile("outputs/jose_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+	writeToFile("outputs/jose_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+	data := result.Data.(map[string]interface{})
+	require.Equal(t, card, data["decryptedCard"])
+	require.Equal(t, "A-1", data["order"].(map[string]interface{})["orderId"])
+	require.NotContains(t, data["encryptedCard"], "4111111111111111")
+}
@@ -0,0 +1,109 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FailActivity fails as soon as another activity reported that it started
+type FailActivity struct {
+	activities.BaseActivity
+	after <-chan string
+}
+
+func (a *FailActivity) Execute(ctx context.Context, args map[string]any) (interface{}, error) {
+	<-a.after
+	return nil, errors.New("carrier unavailable")
+}
+
+func TestParallelActionsWorkflow(t *testing.T) {
+	wait := newWaitActivity("ground", "air", "sea")
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("Wait", wait),
+		config.WithOperation("custom:wait", "Wait"),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(e.GetRegistry())
+	workflow, err := p.ParseFromFile("parallel_actions_workflow.json")
+	require.NoError(t, err)
+
+	results := make(chan *engine.ExecutionResult, 1)
+	go func() {
+		result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"weight": 12}, nil)
+		assert.NoError(t, err)
+		results <- result
+	}()
+
+	// All actions are running at the same time; they complete in reverse order
+	started := map[string]bool{}
+	for range 3 {
+		started[receive(t, wait.started)] = true
+	}
+	require.Equal(t, map[string]bool{"ground": true, "air": true, "sea": true}, started)
+	for _, step := range []string{"sea", "air", "ground"} {
+		close(wait.release[step])
+		require.Equal(t, waitOutcome{step: step}, receive(t, wait.done))
+	}
+
+	result := receive(t, results)
+	writeToFile("outputs/parallel_actions_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+	writeToFile("outputs/parallel_actions_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+	require.Equal(t, map[string]interface{}{
+		"weight": 12,
+		"quotes": map[string]interface{}{"ground": true, "air": true, "sea": true},
+	}, result.Data)
+
+	// Every action has its own debug entry, in definition order
+	actions := result.Debug.States[0].Actions
+	require.Len(t, actions, 3)
+	for i, step := range []string{"ground", "air", "sea"} {
+		require.Equal(t, "Wait", actions[i].ActivityName)
+		require.Equal(t, step, actions[i].Arguments.(map[string]interface{})["step"])
+	}
+}
+
+func TestParallelActionsCancelOnFailure(t *testing.T) {
+	wait := newWaitActivity("ground")
+	e, err := config.Initialize(
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("Wait", wait),
+		config.WithActivity("Fail", &FailActivity{after: wait.started}),
+	)
+	require.NoError(t, err)
+
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromBytes([]byte(`{
+		"id": "parallel-failure", "version": "1.0", "specVersion": "0.8", "start": "QuoteCarriers",
+		"functions": [{ "name": "Wait", "operation": "Wait" }, { "name": "Fail", "operation": "Fail" }],
+		"states": [{
+			"name": "QuoteCarriers", "type": "operation", "actionMode": "parallel", "end": true,
+			"actions": [
+				{ "functionRef": { "refName": "Wait", "arguments": { "step": "ground", "data": "${ .current }" } } },
+				{ "functionRef": { "refName": "Fail" } }
+			]
+		}]
+	}`))
+	require.NoError(t, err)
+
+	_, err = e.Execute(context.Background(), workflow, map[string]interface{}{}, nil)
+	require.ErrorContains(t, err, "carrier unavailable")
+
+	// The action still running was cancelled before the state returned
+	outcome := receive(t, wait.done)
+	require.Equal(t, "ground", outcome.step)
+	require.ErrorIs(t, outcome.err, context.Canceled)
+}
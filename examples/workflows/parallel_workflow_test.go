@@ -0,0 +1,188 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// BranchActivities provides activities used to exercise parallel branch completion and cancellation
+type BranchActivities struct {
+	activities.BaseActivity
+}
+
+// FailFast fails immediately
+func (a *BranchActivities) FailFast(ctx context.Context, args map[string]any) (interface{}, error) {
+	return nil, errors.New("branch failed")
+}
+
+// WaitForCancel blocks until the context is cancelled
+func (a *BranchActivities) WaitForCancel(ctx context.Context, args map[string]any) (interface{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(5 * time.Second):
+		return map[string]interface{}{"slow": true}, nil
+	}
+}
+
+// Succeed returns its arguments
+func (a *BranchActivities) Succeed(ctx context.Context, args map[string]any) (interface{}, error) {
+	return args, nil
+}
+
+func TestParallelWorkflow(t *testing.T) {
+	engine, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(engine.GetRegistry())
+	workflow, err := p.ParseFromFile("parallel_workflow.json")
+	require.NoError(t, err)
+
+	input := map[string]interface{}{
+		"order": map[string]interface{}{
+			"id": "ORD123",
+			"items": []interface{}{
+				map[string]interface{}{"name": "item1", "price": 10},
+				map[string]interface{}{"name": "item2", "price": 15},
+			},
+		},
+	}
+
+	result, err := engine.Execute(context.Background(), workflow, input, nil)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	writeToFile("outputs/parallel_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+	writeToFile("outputs/parallel_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+	require.Equal(t, map[string]interface{}{
+		"id":        "ORD123",
+		"total":     25,
+		"itemCount": 2,
+	}, result.Data)
+
+	// Each branch is recorded separately under the parallel state
+	fanOut := result.Debug.States[1]
+	require.Equal(t, "FanOut", fanOut.Name)
+	require.Len(t, fanOut.Branches, 2)
+	require.Equal(t, "CalculateTotal", fanOut.Branches[0].Name)
+	require.Equal(t, "CountItems", fanOut.Branches[1].Name)
+	require.Len(t, fanOut.Branches[0].Actions, 1)
+	require.Len(t, fanOut.Branches[1].Actions, 1)
+}
+
+func TestParallelWorkflowCompletion(t *testing.T) {
+	newEngine := func(t *testing.T) (*engine.Engine, *parser.Parser) {
+		e, err := config.Initialize(
+			config.WithDebug(true),
+			config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+			config.WithActivityStruct(&BranchActivities{}),
+		)
+		require.NoError(t, err)
+		return e, parser.NewParser(e.GetRegistry())
+	}
+
+	t.Run("Failing Branch Cancels Siblings", func(t *testing.T) {
+		engine, p := newEngine(t)
+		workflow, err := p.ParseFromBytes([]byte(`{
+			"id": "parallel-failure",
+			"specVersion": "0.8",
+			"start": "FanOut",
+			"functions": [{ "name": "WaitForCancel", "operation": "WaitForCancel" }, { "name": "FailFast", "operation": "FailFast" }],
+			"states": [
+				{
+					"name": "FanOut",
+					"type": "parallel",
+					"branches": [
+						{ "name": "Slow", "actions": [ { "functionRef": "WaitForCancel" } ] },
+						{ "name": "Failing", "actions": [ { "functionRef": "FailFast" } ] }
+					],
+					"end": true
+				}
+			]
+		}`))
+		require.NoError(t, err)
+
+		start := time.Now()
+		result, err := engine.Execute(context.Background(), workflow, map[string]interface{}{}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "branch 'Failing' failed")
+		require.Less(t, time.Since(start), 2*time.Second)
+
+		branches := result.Debug.States[0].Branches
+		require.Len(t, branches, 2)
+		require.Contains(t, branches[0].Error, "context canceled")
+	})
+
+	t.Run("At Least Completes Early", func(t *testing.T) {
+		engine, p := newEngine(t)
+		workflow, err := p.ParseFromBytes([]byte(`{
+			"id": "parallel-at-least",
+			"specVersion": "0.8",
+			"start": "FanOut",
+			"functions": [{ "name": "WaitForCancel", "operation": "WaitForCancel" }, { "name": "Succeed", "operation": "Succeed" }],
+			"states": [
+				{
+					"name": "FanOut",
+					"type": "parallel",
+					"completionType": "atLeast",
+					"numCompleted": 1,
+					"branches": [
+						{ "name": "Slow", "actions": [ { "functionRef": "WaitForCancel" } ] },
+						{ "name": "Fast", "actions": [ { "functionRef": { "refName": "Succeed", "arguments": { "fast": "yes" } } } ] }
+					],
+					"end": true
+				}
+			]
+		}`))
+		require.NoError(t, err)
+
+		start := time.Now()
+		result, err := engine.Execute(context.Background(), workflow, map[string]interface{}{"input": "value"}, nil)
+		require.NoError(t, err)
+		require.Less(t, time.Since(start), 2*time.Second)
+		require.Equal(t, map[string]interface{}{"input": "value", "fast": "yes"}, result.Data)
+	})
+
+	t.Run("At Least Tolerates Failures", func(t *testing.T) {
+		engine, p := newEngine(t)
+		workflow, err := p.ParseFromBytes([]byte(`{
+			"id": "parallel-at-least-failure",
+			"specVersion": "0.8",
+			"start": "FanOut",
+			"functions": [{ "name": "FailFast", "operation": "FailFast" }, { "name": "Succeed", "operation": "Succeed" }],
+			"states": [
+				{
+					"name": "FanOut",
+					"type": "parallel",
+					"completionType": "atLeast",
+					"numCompleted": 1,
+					"branches": [
+						{ "name": "Failing", "actions": [ { "functionRef": "FailFast" } ] },
+						{ "name": "Succeeding", "actions": [ { "functionRef": { "refName": "Succeed", "arguments": { "ok": "yes" } } } ] }
+					],
+					"end": true
+				}
+			]
+		}`))
+		require.NoError(t, err)
+
+		result, err := engine.Execute(context.Background(), workflow, map[string]interface{}{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"ok": "yes"}, result.Data)
+	})
+}
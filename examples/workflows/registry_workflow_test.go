@@ -0,0 +1,134 @@
+package workflows
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflowRegistry(t *testing.T) {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithWorkflowDir("registry"),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.0", "2.0"}, e.GetWorkflowRegistry().Versions("greeting"))
+
+	input := map[string]interface{}{"name": "Ada"}
+
+	tests := []struct {
+		name     string
+		id       string
+		version  string
+		expected interface{}
+	}{
+		{
+			name:     "Latest Version",
+			id:       "greeting",
+			expected: map[string]interface{}{"greeting": "Hello, Ada!"},
+		},
+		{
+			name:     "Pinned Version",
+			id:       "greeting",
+			version:  "1.0",
+			expected: map[string]interface{}{"name": "Ada", "greeting": "Hello"},
+		},
+		{
+			name:     "YAML Definition",
+			id:       "farewell",
+			expected: map[string]interface{}{"name": "Ada", "farewell": "Goodbye"},
+		},
+		{
+			name: "Pinned Sub-flow",
+			id:   "welcome",
+			expected: map[string]interface{}{
+				"name":    "Ada",
+				"welcome": map[string]interface{}{"name": "Ada", "greeting": "Hello"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := e.ExecuteByID(context.Background(), tt.id, tt.version, input, nil)
+			require.NoError(t, err)
+
+			writeToFile("outputs/registry_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+			writeToFile("outputs/registry_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			require.Equal(t, tt.expected, result.Data)
+		})
+	}
+
+	t.Run("Unknown Workflow", func(t *testing.T) {
+		_, err := e.ExecuteByID(context.Background(), "greeting", "3.0", input, nil)
+		require.ErrorContains(t, err, string(engine.ErrWorkflowNotFound))
+	})
+
+	t.Run("Duplicate Version", func(t *testing.T) {
+		err := e.LoadWorkflows("registry")
+		require.ErrorContains(t, err, "already registered")
+	})
+}
+
+func TestWorkflowRegistryRejectsInvalidDefinitions(t *testing.T) {
+	greeting, err := os.ReadFile(filepath.Join("registry", "greeting_v1.json"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		definition string
+		err        string
+	}{
+		{
+			name: "Unregistered Activity",
+			definition: `{
+				"id": "broken", "version": "1.0", "specVersion": "0.8", "start": "Send",
+				"functions": [{ "name": "SendSMS", "operation": "SendSMS" }],
+				"states": [{
+					"name": "Send", "type": "operation", "end": true,
+					"actions": [{ "functionRef": { "refName": "SendSMS" } }]
+				}]
+			}`,
+			err: "function 'SendSMS' has operation 'SendSMS', which is neither a registered activity nor mapped to one",
+		},
+		{
+			name: "Unregistered Sub-flow",
+			definition: `{
+				"id": "broken", "version": "1.0", "specVersion": "0.8", "start": "Greet",
+				"states": [{
+					"name": "Greet", "type": "operation", "end": true,
+					"actions": [{ "subFlowRef": { "workflowId": "greeting", "version": "9.9" } }]
+				}]
+			}`,
+			err: "sub-flow 'greeting' version '9.9' is not registered",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "greeting.json"), greeting, 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte(tt.definition), 0644))
+
+			e, err := config.Initialize(config.WithLogger(zap.NewLogger(logger.DebugLevel)))
+			require.NoError(t, err)
+
+			err = e.LoadWorkflows(dir)
+			require.ErrorContains(t, err, tt.err)
+
+			// Nothing is registered when a definition is invalid
+			_, ok := e.GetWorkflowRegistry().Get("greeting", "")
+			require.False(t, ok)
+		})
+	}
+}
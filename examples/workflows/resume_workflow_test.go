@@ -0,0 +1,207 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/persistence"
+	"github.com/kshitiz1403/jsonjuggler/persistence/file"
+	"github.com/kshitiz1403/jsonjuggler/persistence/memory"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/require"
+)
+
+// StepActivity records the steps it executed and adds them to the data it is given.
+// Steps listed in failOnce fail the first time they are executed.
+type StepActivity struct {
+	activities.BaseActivity
+	mu       sync.Mutex
+	executed []string
+	failOnce map[string]bool
+}
+
+func (a *StepActivity) Execute(ctx context.Context, args map[string]any) (interface{}, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	step := cast.ToString(args["step"])
+	a.executed = append(a.executed, step)
+	if a.failOnce[step] {
+		delete(a.failOnce, step)
+		return nil, errors.New("step failed")
+	}
+
+	data := cast.ToStringMap(args["data"])
+	data[step] = true
+	return data, nil
+}
+
+func newResumeEngine(t *testing.T, store persistence.Store, step *StepActivity) *engine.Engine {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("Step", step),
+		config.WithPersistence(store),
+	)
+	require.NoError(t, err)
+	return e
+}
+
+func TestResumeInterruptedWorkflow(t *testing.T) {
+	dir := t.TempDir()
+	input := map[string]interface{}{"orderId": "A-1"}
+
+	// First process: stopped while sleeping in AwaitPayment
+	store, err := file.New(dir)
+	require.NoError(t, err)
+	firstStep := &StepActivity{}
+	first := newResumeEngine(t, store, firstStep)
+
+	workflow, err := parser.NewParser(first.GetRegistry()).ParseFromFile("resume_workflow.json")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	result, err := first.Execute(ctx, workflow, input, nil)
+	require.Error(t, err)
+	require.NotEmpty(t, result.RunID)
+	require.Equal(t, []string{"reserve"}, firstStep.executed)
+
+	checkpoint, err := store.Load(context.Background(), result.RunID)
+	require.NoError(t, err)
+	require.Equal(t, persistence.StatusRunning, checkpoint.Status)
+	require.Equal(t, "AwaitPayment", checkpoint.NextState)
+
+	// Second process: resumes the run from the checkpoint without running ReserveStock again
+	reopened, err := file.New(dir)
+	require.NoError(t, err)
+	secondStep := &StepActivity{}
+	second := newResumeEngine(t, reopened, secondStep)
+
+	resumed, err := second.Resume(context.Background(), result.RunID)
+	require.NoError(t, err)
+
+	writeToFile("outputs/resume_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(resumed.Debug)))
+
+	require.Equal(t, result.RunID, resumed.RunID)
+	require.Equal(t, []string{"confirm"}, secondStep.executed)
+	require.Equal(t, map[string]interface{}{
+		"orderId": "A-1",
+		"reserve": true,
+		"confirm": true,
+	}, resumed.Data)
+
+	require.Len(t, resumed.Debug.States, 2)
+	require.Equal(t, "AwaitPayment", resumed.Debug.States[0].Name)
+
+	checkpoint, err = reopened.Load(context.Background(), result.RunID)
+	require.NoError(t, err)
+	require.Equal(t, persistence.StatusCompleted, checkpoint.Status)
+
+	// A completed run cannot be resumed
+	_, err = second.Resume(context.Background(), result.RunID)
+	require.Error(t, err)
+}
+
+func TestResumeFailedWorkflow(t *testing.T) {
+	store := memory.New()
+	step := &StepActivity{failOnce: map[string]bool{"confirm": true}}
+	e := newResumeEngine(t, store, step)
+
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromFile("resume_workflow.json")
+	require.NoError(t, err)
+
+	result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"orderId": "A-2"}, nil)
+	require.Error(t, err)
+
+	checkpoint, err := store.Load(context.Background(), result.RunID)
+	require.NoError(t, err)
+	require.Equal(t, persistence.StatusFailed, checkpoint.Status)
+	require.Equal(t, "ConfirmOrder", checkpoint.NextState)
+	require.Contains(t, checkpoint.Error, "step failed")
+
+	// Resuming retries the failed state
+	resumed, err := e.Resume(context.Background(), result.RunID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"reserve", "confirm", "confirm"}, step.executed)
+	require.Equal(t, true, resumed.Data.(map[string]interface{})["confirm"])
+}
+
+func TestResumeDiscardsActionResults(t *testing.T) {
+	store := memory.New()
+	step := &StepActivity{failOnce: map[string]bool{"confirm": true}}
+	e := newResumeEngine(t, store, step)
+
+	// The audit step's results are discarded with useResults false, which must survive the checkpoint
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromJSON([]byte(`{
+		"id": "audited-order", "version": "1.0", "specVersion": "0.8", "start": "ConfirmOrder",
+		"functions": [{ "name": "Step", "operation": "Step" }],
+		"states": [{
+			"name": "ConfirmOrder", "type": "operation", "end": true,
+			"actions": [
+				{
+					"functionRef": { "refName": "Step", "arguments": { "step": "audit", "data": {} } },
+					"actionDataFilter": { "useResults": false }
+				},
+				{ "functionRef": { "refName": "Step", "arguments": { "step": "confirm", "data": "${ .current }" } } }
+			]
+		}]
+	}`))
+	require.NoError(t, err)
+
+	result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"orderId": "A-3"}, nil)
+	require.Error(t, err)
+
+	resumed, err := e.Resume(context.Background(), result.RunID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"audit", "confirm", "audit", "confirm"}, step.executed)
+	require.Equal(t, map[string]interface{}{"orderId": "A-3", "confirm": true}, resumed.Data)
+}
+
+func TestResumeUnknownRun(t *testing.T) {
+	e := newResumeEngine(t, memory.New(), &StepActivity{})
+
+	_, err := e.Resume(context.Background(), "unknown")
+	code, ok := engine.GetErrorCode(err)
+	require.True(t, ok)
+	require.Equal(t, engine.ErrRunNotFound, code)
+}
+
+func TestResumeRunningWorkflow(t *testing.T) {
+	store := memory.New()
+	e := newResumeEngine(t, store, &StepActivity{})
+
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromFile("resume_workflow.json")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	result, err := e.Execute(ctx, workflow, map[string]interface{}{"orderId": "A-4"}, nil)
+	require.Error(t, err)
+
+	// The first Resume sleeps in AwaitPayment, the second finds the run still running
+	resumed := make(chan error, 1)
+	go func() {
+		_, err := e.Resume(context.Background(), result.RunID)
+		resumed <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = e.Resume(context.Background(), result.RunID)
+	require.ErrorContains(t, err, "is still running")
+
+	require.NoError(t, <-resumed)
+	checkpoint, err := store.Load(context.Background(), result.RunID)
+	require.NoError(t, err)
+	require.Equal(t, persistence.StatusCompleted, checkpoint.Status)
+}
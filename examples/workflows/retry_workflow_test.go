@@ -0,0 +1,145 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/require"
+)
+
+// FlakyActivity fails the configured number of times before succeeding
+type FlakyActivity struct {
+	activities.BaseActivity
+	mu    sync.Mutex
+	calls int
+}
+
+func (a *FlakyActivity) Execute(ctx context.Context, args map[string]any) (interface{}, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls++
+
+	if args["failures"] == "invalid" {
+		return nil, activities.NewActivityError(activities.ErrInvalidArguments, "Invalid arguments", "Flaky")
+	}
+	if a.calls <= cast.ToInt(args["failures"]) {
+		return nil, errors.New("transient failure")
+	}
+	return map[string]interface{}{"charged": true, "calls": a.calls}, nil
+}
+
+func TestRetryWorkflow(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      interface{}
+		expectError   bool
+		expectedCalls int
+	}{
+		{
+			name:          "Succeeds After Transient Failures",
+			failures:      2,
+			expectedCalls: 3,
+		},
+		{
+			name:          "Gives Up After Max Attempts",
+			failures:      10,
+			expectError:   true,
+			expectedCalls: 4,
+		},
+		{
+			name:          "Does Not Retry Non Retryable Errors",
+			failures:      "invalid",
+			expectError:   true,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &FlakyActivity{}
+			engine, err := config.Initialize(
+				config.WithDebug(true),
+				config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+				config.WithActivity("Flaky", flaky),
+			)
+			require.NoError(t, err)
+
+			workflow, err := parser.NewParser(engine.GetRegistry()).ParseFromFile("retry_workflow.json")
+			require.NoError(t, err)
+
+			result, err := engine.Execute(context.Background(), workflow, map[string]interface{}{"failures": tt.failures}, nil)
+
+			writeToFile("outputs/retry_workflow_"+tt.name+"_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			require.Equal(t, tt.expectedCalls, flaky.calls)
+
+			attempts := result.Debug.States[0].Actions[0].Attempts
+			require.Len(t, attempts, tt.expectedCalls)
+			for i, attempt := range attempts {
+				require.Equal(t, i+1, attempt.Attempt)
+			}
+
+			if tt.expectError {
+				require.Error(t, err)
+				require.NotEmpty(t, attempts[len(attempts)-1].Error)
+				require.Empty(t, attempts[len(attempts)-1].Delay)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, true, result.Data.(map[string]interface{})["charged"])
+			require.NotEmpty(t, attempts[0].Delay)
+			require.Empty(t, attempts[len(attempts)-1].Error)
+		})
+	}
+}
+
+func TestRetryWorkflowCancellation(t *testing.T) {
+	flaky := &FlakyActivity{}
+	engine, err := config.Initialize(
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("Flaky", flaky),
+	)
+	require.NoError(t, err)
+
+	workflow, err := parser.NewParser(engine.GetRegistry()).ParseFromBytes([]byte(`{
+		"id": "retry-cancellation",
+		"specVersion": "0.8",
+		"start": "Charge",
+		"functions": [{ "name": "Flaky", "operation": "Flaky" }],
+		"retries": [
+			{ "name": "Slow", "delay": "PT10S", "maxAttempts": 5 }
+		],
+		"states": [
+			{
+				"name": "Charge",
+				"type": "operation",
+				"actions": [
+					{ "functionRef": { "refName": "Flaky", "arguments": { "failures": 10 } }, "retryRef": "Slow" }
+				],
+				"end": true
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = engine.Execute(ctx, workflow, map[string]interface{}{}, nil)
+	require.Error(t, err)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 2*time.Second)
+	require.Equal(t, 1, flaky.calls)
+}
@@ -0,0 +1,227 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/require"
+)
+
+// waitOutcome is how a step of the WaitActivity ended: released, or with the error of its cancelled context
+type waitOutcome struct {
+	step string
+	err  error
+}
+
+// WaitActivity reports the steps it starts and blocks each of them until it is released or its context is done
+type WaitActivity struct {
+	activities.BaseActivity
+	started chan string
+	done    chan waitOutcome
+	release map[string]chan struct{}
+}
+
+func newWaitActivity(steps ...string) *WaitActivity {
+	a := &WaitActivity{
+		started: make(chan string, len(steps)),
+		done:    make(chan waitOutcome, len(steps)),
+		release: make(map[string]chan struct{}),
+	}
+	for _, step := range steps {
+		a.release[step] = make(chan struct{})
+	}
+	return a
+}
+
+func (a *WaitActivity) Execute(ctx context.Context, args map[string]any) (interface{}, error) {
+	step := cast.ToString(args["step"])
+	a.started <- step
+
+	select {
+	case <-ctx.Done():
+		a.done <- waitOutcome{step: step, err: ctx.Err()}
+		return nil, ctx.Err()
+	case <-a.release[step]:
+		a.done <- waitOutcome{step: step}
+	}
+
+	data := cast.ToStringMap(args["data"])
+	data[step] = true
+	return data, nil
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case value := <-ch:
+		return value
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the workflow")
+	}
+	var zero T
+	return zero
+}
+
+func TestSubFlowWorkflow(t *testing.T) {
+	wait := newWaitActivity("primary", "backup", "confirm")
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("Wait", wait),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(e.GetRegistry())
+	addressValidation, err := p.ParseFromFile("address_validation_workflow.json")
+	require.NoError(t, err)
+	warehouseNotification, err := p.ParseFromFile("warehouse_notification_workflow.json")
+	require.NoError(t, err)
+	workflow, err := p.ParseFromFile("subflow_workflow.json")
+	require.NoError(t, err)
+
+	// Version 2.0 is the latest version, and the one used by the unpinned sub-flow
+	addressValidationV2 := *addressValidation
+	addressValidationV2.Version = "2.0"
+	warehouseNotificationV2 := *warehouseNotification
+	warehouseNotificationV2.Version = "2.0"
+
+	registry := e.GetWorkflowRegistry()
+	require.NoError(t, registry.Register(addressValidation))
+	require.NoError(t, registry.Register(&addressValidationV2))
+	require.NoError(t, registry.Register(warehouseNotification))
+	require.NoError(t, registry.Register(&warehouseNotificationV2))
+	require.Error(t, registry.Register(addressValidation))
+
+	input := map[string]interface{}{
+		"orderId": "A-1",
+		"address": map[string]interface{}{"street": "1 Main St", "zip": "94105"},
+	}
+
+	type execution struct {
+		result *engine.ExecutionResult
+		err    error
+	}
+	executed := make(chan execution, 1)
+	go func() {
+		result, err := e.Execute(context.Background(), workflow, input, nil)
+		executed <- execution{result: result, err: err}
+	}()
+
+	// Both async sub-flows and the parent's last state are running
+	started := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		started[receive(t, wait.started)] = true
+	}
+	require.Equal(t, map[string]bool{"primary": true, "backup": true, "confirm": true}, started)
+
+	close(wait.release["confirm"])
+	require.Equal(t, waitOutcome{step: "confirm"}, receive(t, wait.done))
+	run := receive(t, executed)
+	require.NoError(t, run.err)
+
+	// The primary notification is terminated with its parent, the backup notification continues
+	require.Equal(t, waitOutcome{step: "primary", err: context.Canceled}, receive(t, wait.done))
+	close(wait.release["backup"])
+	require.Equal(t, waitOutcome{step: "backup"}, receive(t, wait.done))
+
+	writeToFile("outputs/subflow_workflow_result.json", []byte(utils.AnyToJSONStringPretty(run.result.Data)))
+	writeToFile("outputs/subflow_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(run.result.Debug)))
+
+	require.Equal(t, map[string]interface{}{
+		"orderId": "A-1",
+		"address": map[string]interface{}{"street": "1 Main St", "zip": "94105"},
+		"addressCheck": map[string]interface{}{
+			"valid":   true,
+			"country": "US",
+		},
+		"confirm": true,
+	}, run.result.Data)
+
+	require.Len(t, run.result.Debug.States, 3)
+	checkAddress := run.result.Debug.States[0]
+	require.Len(t, checkAddress.Actions, 1)
+	validation := checkAddress.Actions[0]
+	require.Equal(t, "validate-address", validation.WorkflowID)
+	require.Equal(t, "2.0", validation.WorkflowVersion)
+	require.NotEmpty(t, validation.RunID)
+	require.NotNil(t, validation.SubFlow)
+	require.Len(t, validation.SubFlow.States, 1)
+	require.Equal(t, "ValidateZip", validation.SubFlow.States[0].Name)
+
+	notify := run.result.Debug.States[1]
+	require.Len(t, notify.Actions, 2)
+	for _, action := range notify.Actions {
+		require.Equal(t, "notify-warehouse", action.WorkflowID)
+		require.Equal(t, "1.0", action.WorkflowVersion)
+		require.NotEmpty(t, action.RunID)
+		require.Nil(t, action.SubFlow)
+	}
+}
+
+func TestSubFlowNotRegistered(t *testing.T) {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("Wait", newWaitActivity()),
+	)
+	require.NoError(t, err)
+
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromFile("subflow_workflow.json")
+	require.NoError(t, err)
+
+	result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"orderId": "A-1"}, nil)
+	require.ErrorContains(t, err, string(engine.ErrWorkflowNotFound))
+	require.Contains(t, result.Debug.States[0].Actions[0].Error, "validate-address")
+}
+
+func TestSubFlowDependents(t *testing.T) {
+	e, err := config.Initialize(
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("Wait", newWaitActivity()),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(e.GetRegistry())
+	addressValidation, err := p.ParseFromFile("address_validation_workflow.json")
+	require.NoError(t, err)
+	warehouseNotification, err := p.ParseFromFile("warehouse_notification_workflow.json")
+	require.NoError(t, err)
+	workflow, err := p.ParseFromFile("subflow_workflow.json")
+	require.NoError(t, err)
+
+	addressValidationV2 := *addressValidation
+	addressValidationV2.Version = "2.0"
+	warehouseNotificationV2 := *warehouseNotification
+	warehouseNotificationV2.Version = "2.0"
+
+	registry := e.GetWorkflowRegistry()
+	require.NoError(t, registry.Load(addressValidation, &addressValidationV2, warehouseNotification, workflow))
+
+	// The pinned version can neither be removed nor replaced by another version
+	err = registry.Replace(warehouseNotification, nil)
+	require.ErrorContains(t, err, string(engine.ErrWorkflowNotFound))
+	require.ErrorContains(t, err, "subflow-workflow")
+	require.Error(t, registry.Replace(warehouseNotification, &warehouseNotificationV2))
+	_, ok := registry.Get("notify-warehouse", "1.0")
+	require.True(t, ok)
+	_, ok = registry.Get("notify-warehouse", "2.0")
+	require.False(t, ok)
+
+	// The unpinned sub-flow still resolves while any version of it is registered
+	require.NoError(t, registry.Replace(addressValidation, nil))
+	require.Error(t, registry.Replace(&addressValidationV2, nil))
+	require.Equal(t, []string{"2.0"}, registry.Versions("validate-address"))
+
+	// Once the dependent is removed, so can be its sub-flows
+	require.NoError(t, registry.Replace(workflow, nil))
+	require.NoError(t, registry.Replace(warehouseNotification, nil))
+}
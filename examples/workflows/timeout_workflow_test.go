@@ -0,0 +1,246 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/engine"
+	"github.com/kshitiz1403/jsonjuggler/events"
+	"github.com/kshitiz1403/jsonjuggler/events/channel"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/require"
+)
+
+// SlowActivity returns its result argument after the given number of seconds, unless the context is done first
+type SlowActivity struct {
+	activities.BaseActivity
+}
+
+func (a *SlowActivity) Execute(ctx context.Context, args map[string]any) (interface{}, error) {
+	select {
+	case <-time.After(time.Duration(cast.ToFloat64(args["seconds"]) * float64(time.Second))):
+		return args["result"], nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func newTimeoutEngine(t *testing.T) *engine.Engine {
+	e, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+		config.WithActivity("Slow", &SlowActivity{}),
+	)
+	require.NoError(t, err)
+	return e
+}
+
+// requireTimeout asserts that err is a timeout error caused by the named timeout
+func requireTimeout(t *testing.T, err error, timeout string) {
+	t.Helper()
+	require.Error(t, err)
+
+	var wfErr *engine.WorkflowError
+	for current := err; errors.As(current, &wfErr); current = wfErr.Cause {
+		if wfErr.Code == engine.ErrTimeout {
+			require.Equal(t, timeout, wfErr.Context.AdditionalInfo["timeout"])
+			return
+		}
+	}
+	t.Fatalf("expected a %s error, got: %v", timeout, err)
+}
+
+func TestTimeoutWorkflow(t *testing.T) {
+	tests := []struct {
+		name           string
+		latency        float64
+		expectedSource string
+	}{
+		{
+			name:           "Completes Within Timeout",
+			latency:        0,
+			expectedSource: "live",
+		},
+		{
+			name:           "Action Timeout Routed Through OnErrors",
+			latency:        5,
+			expectedSource: "cache",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTimeoutEngine(t)
+			workflow, err := parser.NewParser(e.GetRegistry()).ParseFromFile("timeout_workflow.json")
+			require.NoError(t, err)
+
+			start := time.Now()
+			result, err := e.Execute(context.Background(), workflow, map[string]interface{}{"latency": tt.latency}, nil)
+			require.NoError(t, err)
+			require.Less(t, time.Since(start), 3*time.Second)
+
+			writeToFile("outputs/timeout_workflow_"+tt.name+"_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			require.Equal(t, tt.expectedSource, result.Data.(map[string]interface{})["source"])
+		})
+	}
+}
+
+func TestStateExecTimeout(t *testing.T) {
+	e := newTimeoutEngine(t)
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromBytes([]byte(`{
+		"id": "state-timeout",
+		"specVersion": "0.8",
+		"start": "Wait",
+		"states": [
+			{
+				"name": "Wait",
+				"type": "sleep",
+				"duration": "PT10S",
+				"timeouts": { "stateExecTimeout": { "total": "PT1S" } },
+				"onErrors": [ { "errorRef": "TIMEOUT", "transition": "TimedOut" } ],
+				"end": true
+			},
+			{
+				"name": "TimedOut",
+				"type": "inject",
+				"data": { "status": "timed out" },
+				"end": true
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	start := time.Now()
+	result, err := e.Execute(context.Background(), workflow, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 3*time.Second)
+	require.Equal(t, "timed out", result.Data.(map[string]interface{})["status"])
+
+	// The timeout is recorded on the state that exceeded it
+	require.Contains(t, result.Debug.States[0].Error, "TIMEOUT")
+}
+
+func TestWorkflowExecTimeout(t *testing.T) {
+	e := newTimeoutEngine(t)
+	workflow, err := parser.NewParser(e.GetRegistry()).ParseFromBytes([]byte(`{
+		"id": "workflow-timeout",
+		"specVersion": "0.8",
+		"start": "Call",
+		"functions": [{ "name": "Slow", "operation": "Slow" }],
+		"timeouts": {
+			"workflowExecTimeout": { "duration": "PT1S", "interrupt": true, "runBefore": "Cleanup" }
+		},
+		"states": [
+			{
+				"name": "Call",
+				"type": "operation",
+				"actions": [ { "functionRef": { "refName": "Slow", "arguments": { "seconds": 10 } } } ],
+				"onErrors": [ { "errorRef": "DefaultErrorRef", "transition": "Handled" } ],
+				"end": true
+			},
+			{
+				"name": "Handled",
+				"type": "inject",
+				"data": { "status": "handled" },
+				"end": true
+			},
+			{
+				"name": "Cleanup",
+				"type": "inject",
+				"data": { "status": "cleaned up" },
+				"end": true
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	start := time.Now()
+	result, err := e.Execute(context.Background(), workflow, map[string]interface{}{}, nil)
+	require.Less(t, time.Since(start), 3*time.Second)
+
+	// A workflow timeout is not routed through the state's onErrors
+	requireTimeout(t, err, "workflowExecTimeout")
+	code, ok := engine.GetErrorCode(err)
+	require.True(t, ok)
+	require.Equal(t, engine.ErrTimeout, code)
+
+	// The runBefore state still runs before the workflow is terminated
+	require.Equal(t, "cleaned up", result.Data.(map[string]interface{})["status"])
+}
+
+func TestEventAndCallbackStateTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   string
+		timeout string
+	}{
+		{
+			name: "Event Action Timeout",
+			state: `{
+				"name": "Process", "type": "event", "end": true,
+				"onEvents": [{
+					"eventRefs": ["Ready"],
+					"actions": [{ "functionRef": { "refName": "Slow", "arguments": { "seconds": 10 } } }]
+				}],
+				"timeouts": { "actionExecTimeout": "PT1S" }
+			}`,
+			timeout: "actionExecTimeout",
+		},
+		{
+			name: "Callback Action Timeout",
+			state: `{
+				"name": "Process", "type": "callback", "end": true,
+				"action": { "functionRef": { "refName": "Slow", "arguments": { "seconds": 10 } } },
+				"eventRef": "Ready",
+				"timeouts": { "actionExecTimeout": "PT1S" }
+			}`,
+			timeout: "actionExecTimeout",
+		},
+		{
+			name: "Callback State Timeout",
+			state: `{
+				"name": "Process", "type": "callback", "end": true,
+				"action": { "functionRef": { "refName": "Slow", "arguments": { "seconds": 0 } } },
+				"eventRef": "Ready",
+				"timeouts": { "stateExecTimeout": { "total": "PT1S" } }
+			}`,
+			timeout: "stateExecTimeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := channel.New()
+			defer bus.Close()
+			e, err := config.Initialize(
+				config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+				config.WithActivity("Slow", &SlowActivity{}),
+				config.WithEventBus(bus),
+			)
+			require.NoError(t, err)
+
+			workflow, err := parser.NewParser(e.GetRegistry()).ParseFromBytes([]byte(`{
+				"id": "event-timeout", "specVersion": "0.8", "start": "Process",
+				"functions": [{ "name": "Slow", "operation": "Slow" }],
+				"events": [{ "name": "Ready", "source": "jobs", "type": "com.jobs.ready" }],
+				"states": [` + tt.state + `]
+			}`))
+			require.NoError(t, err)
+			require.NoError(t, bus.Publish(context.Background(), events.Event{Source: "jobs", Type: "com.jobs.ready"}))
+
+			start := time.Now()
+			_, err = e.Execute(context.Background(), workflow, map[string]interface{}{}, nil)
+			require.Less(t, time.Since(start), 3*time.Second)
+			requireTimeout(t, err, tt.timeout)
+		})
+	}
+}
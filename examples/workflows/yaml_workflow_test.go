@@ -0,0 +1,218 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/config"
+	"github.com/kshitiz1403/jsonjuggler/logger"
+	"github.com/kshitiz1403/jsonjuggler/logger/zap"
+	"github.com/kshitiz1403/jsonjuggler/parser"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYAMLWorkflow(t *testing.T) {
+	engine, err := config.Initialize(
+		config.WithDebug(true),
+		config.WithLogger(zap.NewLogger(logger.DebugLevel)),
+	)
+	require.NoError(t, err)
+
+	p := parser.NewParser(engine.GetRegistry())
+	workflow, err := p.ParseFromFile("yaml_workflow.yaml")
+	require.NoError(t, err)
+
+	// The format is detected from the content as well
+	source, err := os.ReadFile("yaml_workflow.yaml")
+	require.NoError(t, err)
+	detected, err := p.ParseFromBytes(source)
+	require.NoError(t, err)
+	require.Equal(t, workflow.Workflow, detected.Workflow)
+
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "Freight",
+			input:    map[string]interface{}{"weight": 30},
+			expected: map[string]interface{}{"carrier": "freight", "price": 60},
+		},
+		{
+			name:     "Parcel",
+			input:    map[string]interface{}{"weight": 5},
+			expected: map[string]interface{}{"weight": 5, "carrier": "parcel"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.Execute(context.Background(), workflow, tt.input, nil)
+			require.NoError(t, err)
+
+			writeToFile("outputs/yaml_workflow_result.json", []byte(utils.AnyToJSONStringPretty(result.Data)))
+			writeToFile("outputs/yaml_workflow_debug.json", []byte(utils.AnyToJSONStringPretty(result.Debug)))
+
+			require.Equal(t, tt.expected, result.Data)
+		})
+	}
+}
+
+func TestYAMLWorkflowErrors(t *testing.T) {
+	engine, err := config.Initialize(config.WithLogger(zap.NewLogger(logger.DebugLevel)))
+	require.NoError(t, err)
+	p := parser.NewParser(engine.GetRegistry())
+
+	tests := []struct {
+		name       string
+		definition string
+		line       int
+		column     int
+		err        string
+	}{
+		{
+			name: "Syntax Error",
+			definition: `id: broken
+version: "1.0"
+  specVersion: "0.8"
+`,
+			line:   2,
+			column: 1,
+			err:    "did not find expected key",
+		},
+		{
+			// YAML reports no column, the error is located at the start of the line
+			name: "Syntax Error In State",
+			definition: `id: broken
+version: "1.0"
+specVersion: "0.8"
+start: Wait
+states:
+  - name: Wait: now
+    type: sleep
+`,
+			line:   6,
+			column: 3,
+			err:    "mapping values are not allowed in this context",
+		},
+		{
+			name: "Undeclared Function",
+			definition: `id: broken
+version: "1.0"
+specVersion: "0.8"
+start: Notify
+states:
+  - name: Notify
+    type: operation
+    actions:
+      - functionRef:
+          refName: SendSMS
+    end: true
+`,
+			line:   10,
+			column: 20,
+			err:    "state 'Notify' references function 'SendSMS', which is not declared in functions",
+		},
+		{
+			name: "Missing Required Field",
+			definition: `id: broken
+version: "1.0"
+specVersion: "0.8"
+start: Wait
+states:
+  - name: Wait
+    type: sleep
+    end: true
+`,
+			line:   6,
+			column: 5,
+			err:    "'Duration' failed on the 'required' tag",
+		},
+		{
+			name: "Wrong Type",
+			definition: `id: broken
+version: "1.0"
+specVersion: "0.8"
+start: Inject
+states:
+  - name: Inject
+    type: inject
+    data:
+      - 1
+    end: true
+`,
+			line:   9,
+			column: 7,
+			err:    "cannot unmarshal array",
+		},
+		{
+			// The metadata of the first state has a valid number under the same key
+			name: "Wrong Type In Later State",
+			definition: `id: broken
+version: "1.0"
+specVersion: "0.8"
+start: Wait
+states:
+  - name: Wait
+    type: sleep
+    duration: PT1S
+    metadata:
+      duration: 5
+    transition: Retry
+  - name: Retry
+    type: sleep
+    duration: 5
+    end: true
+`,
+			line:   14,
+			column: 15,
+			err:    "cannot unmarshal number",
+		},
+		{
+			// The decoder stops at the first of the two mismatches
+			name: "Wrong Type In Two States",
+			definition: `id: broken
+version: "1.0"
+specVersion: "0.8"
+start: Wait
+states:
+  - name: Wait
+    type: sleep
+    duration: 5
+    transition: Retry
+  - name: Retry
+    type: sleep
+    duration: 5
+    end: true
+`,
+			line:   8,
+			column: 15,
+			err:    "cannot unmarshal number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.ParseFromYAML([]byte(tt.definition))
+			require.ErrorContains(t, err, tt.err)
+
+			// Syntax and type errors are a ParseError, validation problems are located individually
+			var parseErr *parser.ParseError
+			var validationErr *parser.ValidationError
+			switch {
+			case errors.As(err, &parseErr):
+				require.Equal(t, tt.line, parseErr.Line)
+				require.Equal(t, tt.column, parseErr.Column)
+			case errors.As(err, &validationErr):
+				require.Equal(t, tt.line, validationErr.Line)
+				require.Equal(t, tt.column, validationErr.Column)
+			default:
+				t.Fatalf("error is not located: %v", err)
+			}
+		})
+	}
+}
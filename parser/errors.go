@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError is a problem in a workflow definition that parsed but is not valid
+type ValidationError struct {
+	// State is the name of the state with the problem, or "" when the problem is outside of the states
+	State string
+	// Path is the JSON path of the offending value, e.g. "states[0].actions[1].functionRef.refName"
+	Path string
+	// Message describes the problem
+	Message string
+	// Line and Column locate the offending value in a YAML definition. They are 0 when unknown.
+	Line   int
+	Column int
+}
+
+func (e *ValidationError) Error() string {
+	switch {
+	case e.Line == 0:
+		return e.Message
+	case e.Column == 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ValidationErrors are all the problems found in a workflow definition
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d problems in workflow definition: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the individual problems
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// covers reports whether one of the problems is at the path or at a parent of it
+func (e ValidationErrors) covers(path string) bool {
+	for _, err := range e {
+		if path == err.Path || strings.HasPrefix(path, err.Path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseError is an error in a YAML workflow definition, located at the line and column of the offending node.
+// The column is 0 when only the line is known.
+type ParseError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+// Unwrap returns the located error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kshitiz1403/jsonjuggler/activities"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+)
+
+// RESTActivity is the activity that sends the requests of rest functions
+const RESTActivity = "HTTPRequest"
+
+// Function is a workflow function resolved to the activity that runs it
+type Function struct {
+	sw.Function
+	// Activity is the name of the registered activity that runs the function, "" for expression functions, which
+	// the engine evaluates itself
+	Activity string
+	// OpenAPI is the OpenAPI operation a rest function invokes, nil for the other types
+	OpenAPI *OpenAPIOperation
+}
+
+// FunctionType returns the type of the function. Untyped functions are rest functions, as in the specification, when
+// their operation has the "<document>#<operationId>" form of an OpenAPI operation, and custom functions otherwise, so
+// that untyped declarations such as {"name": "JQ", "operation": "jq:transform"} resolve to registered activities.
+func FunctionType(function sw.Function) sw.FunctionType {
+	if function.Type != "" {
+		return function.Type
+	}
+	if isOpenAPIReference(function.Operation) {
+		return sw.FunctionTypeREST
+	}
+	return sw.FunctionTypeCustom
+}
+
+// ResolveFunction resolves the function the workflow declares under the name to the registered activity that runs
+// it. The operation of a custom function is an activity name or an operation mapped to one with
+// Registry.RegisterOperation; rest functions invoke an OpenAPI operation, "<document>#<operationId>", with the
+// HTTPRequest activity, and their document is loaded within the context; expression functions have no activity,
+// their operation is a JQ expression.
+func ResolveFunction(ctx context.Context, registry *activities.Registry, workflow *sw.Workflow, name string) (*Function, error) {
+	for _, function := range workflow.Functions {
+		if function.Name != name {
+			continue
+		}
+		resolved, err := resolveActivity(registry, function)
+		if err != nil {
+			return nil, err
+		}
+		if FunctionType(function) == sw.FunctionTypeREST {
+			operation, err := LoadOpenAPIOperation(ctx, function.Operation)
+			if err != nil {
+				return nil, fmt.Errorf("rest function '%s': %w", function.Name, err)
+			}
+			resolved.OpenAPI = operation
+		}
+		return resolved, nil
+	}
+	return nil, fmt.Errorf("function '%s' is not declared in the workflow's functions", name)
+}
+
+// resolveActivity resolves a function declaration to the registered activity that runs it, without loading the
+// OpenAPI operation of rest functions, which can require a request
+func resolveActivity(registry *activities.Registry, function sw.Function) (*Function, error) {
+	resolved := &Function{Function: function}
+	switch FunctionType(function) {
+	case sw.FunctionTypeCustom:
+		name, ok := registry.LookupOperation(function.Operation)
+		if !ok {
+			return nil, fmt.Errorf("function '%s' has operation '%s', which is neither a registered activity nor mapped to one", function.Name, function.Operation)
+		}
+		resolved.Activity = name
+	case sw.FunctionTypeREST:
+		if !isOpenAPIReference(function.Operation) {
+			return nil, fmt.Errorf("rest function '%s': operation '%s' is not of the form <document>#<operationId>", function.Name, function.Operation)
+		}
+		resolved.Activity = RESTActivity
+	case sw.FunctionTypeExpression:
+		return resolved, nil
+	default:
+		return nil, fmt.Errorf("function '%s' has type '%s', which is not supported", function.Name, function.Type)
+	}
+
+	if _, ok := registry.Get(resolved.Activity); !ok {
+		return nil, fmt.Errorf("function '%s' is run by activity '%s', which is not registered", function.Name, resolved.Activity)
+	}
+	return resolved, nil
+}
+
+// ExpressionFunctions returns the expressions of the workflow's expression functions by function name
+func ExpressionFunctions(workflow *sw.Workflow) map[string]string {
+	functions := make(map[string]string)
+	for _, function := range workflow.Functions {
+		if FunctionType(function) == sw.FunctionTypeExpression {
+			functions[function.Name] = function.Operation
+		}
+	}
+	return functions
+}
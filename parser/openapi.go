@@ -0,0 +1,239 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIOperation is the operation of an OpenAPI document that a rest function invokes
+type OpenAPIOperation struct {
+	// ID is the operationId of the operation
+	ID string
+	// Method is the upper case HTTP method, e.g. "GET"
+	Method string
+	// URL is the URL of the first server joined with the path of the operation, e.g. "https://example.com/pets/{petId}"
+	URL string
+	// Parameters are the path, query and header parameters of the operation
+	Parameters []OpenAPIParameter
+	// HasBody reports whether the operation takes a request body
+	HasBody bool
+}
+
+// OpenAPIParameter is a parameter of an OpenAPI operation
+type OpenAPIParameter struct {
+	Name string `json:"name"`
+	// In is where the parameter goes: "path", "query", "header" or "cookie"
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Ref      string `json:"$ref"`
+}
+
+// openAPIDocument is the part of an OpenAPI 3 document needed to invoke its operations
+type openAPIDocument struct {
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Parameters map[string]OpenAPIParameter `json:"parameters"`
+	} `json:"components"`
+}
+
+// openAPIOperation is an operation object of an OpenAPI document
+type openAPIOperation struct {
+	OperationID string             `json:"operationId"`
+	Parameters  []OpenAPIParameter `json:"parameters"`
+	RequestBody json.RawMessage    `json:"requestBody"`
+}
+
+// openAPITimeout bounds the time to fetch an OpenAPI document from a URL
+const openAPITimeout = 30 * time.Second
+
+// openAPIClient fetches OpenAPI documents from URLs
+var openAPIClient = &http.Client{Timeout: openAPITimeout}
+
+// openAPIMethods are the keys of a path item that are operations
+var openAPIMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true,
+}
+
+// LoadOpenAPIOperation loads the operation a rest function invokes. The operation is given as
+// "<document>#<operationId>", where the document is the file path or URL of an OpenAPI 3 document in JSON or
+// YAML. Relative paths are resolved against the working directory, and documents at a URL are fetched within the
+// context and openAPITimeout.
+func LoadOpenAPIOperation(ctx context.Context, operation string) (*OpenAPIOperation, error) {
+	if !isOpenAPIReference(operation) {
+		return nil, fmt.Errorf("operation '%s' is not of the form <document>#<operationId>", operation)
+	}
+	location, operationID, _ := strings.Cut(operation, "#")
+
+	document, err := loadOpenAPIDocument(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI document '%s': %w", location, err)
+	}
+	if len(document.Servers) == 0 {
+		return nil, fmt.Errorf("OpenAPI document '%s' declares no servers", location)
+	}
+	server, err := serverURL(location, document.Servers[0].URL)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAPI document '%s' has an invalid server URL: %w", location, err)
+	}
+
+	// Visit the paths in order so that a duplicate operationId always resolves to the same operation
+	paths := make([]string, 0, len(document.Paths))
+	for path := range document.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		item := document.Paths[path]
+		var shared []OpenAPIParameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("OpenAPI document '%s' has invalid parameters for path '%s': %w", location, path, err)
+			}
+		}
+
+		for method, raw := range item {
+			if !openAPIMethods[method] {
+				continue
+			}
+			var op openAPIOperation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("OpenAPI document '%s' has an invalid %s operation for path '%s': %w", location, method, path, err)
+			}
+			if op.OperationID != operationID {
+				continue
+			}
+
+			parameters, err := document.parameters(append(shared, op.Parameters...))
+			if err != nil {
+				return nil, fmt.Errorf("OpenAPI document '%s' operation '%s': %w", location, operationID, err)
+			}
+			return &OpenAPIOperation{
+				ID:         operationID,
+				Method:     strings.ToUpper(method),
+				URL:        strings.TrimSuffix(server, "/") + path,
+				Parameters: parameters,
+				HasBody:    len(op.RequestBody) > 0 && string(op.RequestBody) != "null",
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("OpenAPI document '%s' has no operation '%s'", location, operationID)
+}
+
+// parameters resolves references to the document's components. Operation parameters override the path item
+// parameters of the same name and location, which come first.
+func (d *openAPIDocument) parameters(declared []OpenAPIParameter) ([]OpenAPIParameter, error) {
+	var parameters []OpenAPIParameter
+	index := make(map[string]int)
+	for _, parameter := range declared {
+		if parameter.Ref != "" {
+			name, ok := strings.CutPrefix(parameter.Ref, "#/components/parameters/")
+			if !ok {
+				return nil, fmt.Errorf("unsupported parameter reference '%s'", parameter.Ref)
+			}
+			component, ok := d.Components.Parameters[name]
+			if !ok {
+				return nil, fmt.Errorf("parameter reference '%s' does not exist", parameter.Ref)
+			}
+			parameter = component
+		}
+
+		key := parameter.In + ":" + parameter.Name
+		if i, ok := index[key]; ok {
+			parameters[i] = parameter
+			continue
+		}
+		index[key] = len(parameters)
+		parameters = append(parameters, parameter)
+	}
+	return parameters, nil
+}
+
+// loadOpenAPIDocument reads and decodes the OpenAPI document at the file path or URL
+func loadOpenAPIDocument(ctx context.Context, location string) (*openAPIDocument, error) {
+	var data []byte
+	var err error
+	if isURL(location) {
+		data, err = fetch(ctx, location)
+	} else {
+		data, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so both formats decode the same way
+	var source interface{}
+	if err := yaml.Unmarshal(data, &source); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+	document := &openAPIDocument{}
+	if err := json.Unmarshal(raw, document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// fetch returns the body of a successful GET request to the URL
+func fetch(ctx context.Context, location string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := openAPIClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// serverURL resolves a relative server URL against the URL of the document it is declared in
+func serverURL(location string, server string) (string, error) {
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return "", err
+	}
+	if serverURL.IsAbs() {
+		return server, nil
+	}
+	if !isURL(location) {
+		return "", fmt.Errorf("relative server URL '%s' in a document that is not loaded from a URL", server)
+	}
+	documentURL, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	return documentURL.ResolveReference(serverURL).String(), nil
+}
+
+// isOpenAPIReference reports whether the operation has the "<document>#<operationId>" form of an OpenAPI operation
+func isOpenAPIReference(operation string) bool {
+	location, operationID, ok := strings.Cut(operation, "#")
+	return ok && location != "" && operationID != ""
+}
+
+// isURL reports whether the location is an HTTP(S) URL rather than a file path
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
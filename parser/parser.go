@@ -1,12 +1,23 @@
 package parser
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/kshitiz1403/jsonjuggler/activities"
 	sw "github.com/serverlessworkflow/sdk-go/v2/model"
-	"github.com/serverlessworkflow/sdk-go/v2/parser"
+)
+
+// Extensions of workflow definition files
+const (
+	extJSON = ".json"
+	extYAML = ".yaml"
+	extYML  = ".yml"
 )
 
 // Parser handles workflow DSL parsing and validation
@@ -21,56 +32,188 @@ func NewParser(registry *activities.Registry) *Parser {
 	}
 }
 
-// ParseFromFile parses a workflow from a JSON file
-func (p *Parser) ParseFromFile(filePath string) (*sw.Workflow, error) {
+// ParseFromFile parses a workflow from a JSON or YAML file. The format is detected from the file extension
+// (.json, .yaml or .yml), or from the content for other extensions. Relative paths in the definition, e.g. of
+// schemas, are resolved against the directory of the file.
+func (p *Parser) ParseFromFile(filePath string) (*Workflow, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read workflow file: %w", err)
 	}
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workflow directory: %w", err)
+	}
+
+	var workflow *Workflow
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case extJSON:
+		workflow, err = p.ParseFromJSON(data)
+	case extYAML, extYML:
+		workflow, err = p.ParseFromYAML(data)
+	default:
+		workflow, err = p.ParseFromBytes(data)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	return p.ParseFromBytes(data)
+	workflow.Dir = dir
+	return workflow, nil
 }
 
-// ParseFromBytes parses a workflow from JSON bytes
-func (p *Parser) ParseFromBytes(data []byte) (*sw.Workflow, error) {
-	workflow, err := parser.FromJSONSource(data)
+// ParseDir parses every workflow file in the directory and its subdirectories, in lexical order of their paths.
+// It fails on the first file that cannot be parsed or validated.
+func (p *Parser) ParseDir(dir string) ([]*Workflow, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && isWorkflowFile(path) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
 	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow directory: %w", err)
+	}
+
+	workflows := make([]*Workflow, 0, len(paths))
+	for _, path := range paths {
+		workflow, err := p.ParseFromFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		workflows = append(workflows, workflow)
+	}
+	return workflows, nil
+}
+
+// isWorkflowFile reports whether the file is a workflow definition, judging by its extension
+func isWorkflowFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case extJSON, extYAML, extYML:
+		return true
+	}
+	return false
+}
+
+// ParseFromBytes parses a workflow from JSON or YAML bytes. Content starting with '{' is parsed as JSON,
+// anything else as YAML.
+func (p *Parser) ParseFromBytes(data []byte) (*Workflow, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return p.ParseFromJSON(data)
+	}
+	return p.ParseFromYAML(data)
+}
+
+// ParseFromJSON parses a workflow from JSON bytes. Invalid definitions fail with ValidationErrors listing
+// every problem found.
+func (p *Parser) ParseFromJSON(data []byte) (*Workflow, error) {
+	workflow := &sw.Workflow{}
+	if err := json.Unmarshal(data, workflow); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+	if err := defaultEventDataFilters(workflow, data); err != nil {
 		return nil, fmt.Errorf("failed to parse workflow: %w", err)
 	}
 
-	// Additional custom validation if needed
-	if err := p.validateCustomRules(workflow); err != nil {
+	if err := p.validate(workflow); err != nil {
 		return nil, err
 	}
 
-	return workflow, nil
+	return &Workflow{Workflow: *workflow, Source: bytes.Clone(data)}, nil
+}
+
+// declaredFilters are the event data filters a definition declares, by the path of the state and its event
+type declaredFilters struct {
+	States []struct {
+		EventDataFilter json.RawMessage `json:"eventDataFilter"`
+		OnEvents        []struct {
+			EventDataFilter json.RawMessage `json:"eventDataFilter"`
+		} `json:"onEvents"`
+		EventConditions []struct {
+			EventDataFilter json.RawMessage `json:"eventDataFilter"`
+		} `json:"eventConditions"`
+	} `json:"states"`
 }
 
-// validateCustomRules performs any additional validation beyond what the SDK provides
-func (p *Parser) validateCustomRules(workflow *sw.Workflow) error {
-	// Track all unique activities referenced in the workflow
-	referencedActivities := make(map[string]bool)
-
-	// Collect all activity references from states
-	for _, state := range workflow.States {
-		switch state.GetType() {
-		case sw.StateTypeOperation:
-			opState := state.(*sw.OperationState)
-			for _, action := range opState.Actions {
-				if action.FunctionRef == nil || action.FunctionRef.RefName == "" {
-					return fmt.Errorf("state '%s' has an action with missing function reference", state.GetName())
+// defaultEventDataFilters sets useData of the event data filters the definition leaves out to true, the default of
+// the specification. The SDK only defaults it when the filter is present, so a left out filter could not be told
+// apart from one that sets useData to false.
+func defaultEventDataFilters(workflow *sw.Workflow, source []byte) error {
+	var declared declaredFilters
+	if err := json.Unmarshal(source, &declared); err != nil {
+		return err
+	}
+	if len(declared.States) != len(workflow.States) {
+		return nil
+	}
+
+	for i, state := range workflow.States {
+		filters := declared.States[i]
+		switch state := state.(type) {
+		case *sw.CallbackState:
+			if filters.EventDataFilter == nil {
+				state.EventDataFilter.UseData = true
+			}
+		case *sw.EventState:
+			for j := range state.OnEvents {
+				if j >= len(filters.OnEvents) || filters.OnEvents[j].EventDataFilter == nil {
+					state.OnEvents[j].EventDataFilter.UseData = true
+				}
+			}
+		case *sw.SwitchState:
+			for j := range state.EventConditions {
+				if j >= len(filters.EventConditions) || filters.EventConditions[j].EventDataFilter == nil {
+					state.EventConditions[j].EventDataFilter.UseData = true
 				}
-				referencedActivities[action.FunctionRef.RefName] = true
 			}
 		}
 	}
+	return nil
+}
+
+// stateAction is an action of a state, with its JSON path relative to the state, e.g. "branches[0].actions[1]"
+type stateAction struct {
+	sw.Action
+	path string
+}
 
-	// Validate all referenced activities are registered
-	for activityName := range referencedActivities {
-		if _, exists := p.registry.Get(activityName); !exists {
-			return fmt.Errorf("activity '%s' is referenced in workflow but not registered", activityName)
+// stateActions returns all actions defined by a state with their paths, including the actions of nested branches
+func stateActions(state sw.State) []stateAction {
+	var actions []stateAction
+	appendActions := func(prefix string, list []sw.Action) {
+		for i, action := range list {
+			actions = append(actions, stateAction{Action: action, path: fmt.Sprintf("%sactions[%d]", prefix, i)})
 		}
 	}
 
-	return nil
+	switch state.GetType() {
+	case sw.StateTypeOperation:
+		appendActions("", state.(*sw.OperationState).Actions)
+	case sw.StateTypeParallel:
+		for i, branch := range state.(*sw.ParallelState).Branches {
+			appendActions(fmt.Sprintf("branches[%d].", i), branch.Actions)
+		}
+	case sw.StateTypeForEach:
+		appendActions("", state.(*sw.ForEachState).Actions)
+	case sw.StateTypeCallback:
+		actions = append(actions, stateAction{Action: state.(*sw.CallbackState).Action, path: "action"})
+	case sw.StateTypeEvent:
+		for i, onEvents := range state.(*sw.EventState).OnEvents {
+			appendActions(fmt.Sprintf("onEvents[%d].", i), onEvents.Actions)
+		}
+	}
+	return actions
+}
+
+// StateActions returns all actions defined by a state, including the actions of nested branches
+func StateActions(state sw.State) []sw.Action {
+	var actions []sw.Action
+	for _, action := range stateActions(state) {
+		actions = append(actions, action.Action)
+	}
+	return actions
 }
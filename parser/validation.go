@@ -0,0 +1,448 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/itchyny/gojq"
+	"github.com/kshitiz1403/jsonjuggler/utils"
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+	val "github.com/serverlessworkflow/sdk-go/v2/validator"
+)
+
+// statePath matches the state a JSON path points into, e.g. "states[2].actions[0]"
+var statePath = regexp.MustCompile(`^states\[(\d+)\]`)
+
+// validate checks the workflow against the rules of the SDK and the rules of the engine. All problems found
+// are returned together as ValidationErrors.
+func (p *Parser) validate(workflow *sw.Workflow) error {
+	v := p.validateCustomRules(workflow)
+	custom := v.errs
+
+	var errs ValidationErrors
+	if err := val.GetValidator().Struct(workflow); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			return err
+		}
+		for _, fieldErr := range fieldErrs {
+			path := namespacePath(fieldErr.Namespace())
+			// The engine's own rules report some of the SDK's problems with more detail
+			if custom.covers(path) {
+				continue
+			}
+			errs = append(errs, &ValidationError{
+				State:   stateName(workflow, path),
+				Path:    path,
+				Message: fmt.Sprintf("field validation for '%s' failed on the '%s' tag", fieldErr.Field(), fieldErr.Tag()),
+			})
+		}
+	}
+	errs = append(errs, custom...)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// stateName returns the name of the state a JSON path points into, or "" when it is outside of the states
+func stateName(workflow *sw.Workflow, path string) string {
+	match := statePath.FindStringSubmatch(path)
+	if match == nil {
+		return ""
+	}
+	i, _ := strconv.Atoi(match[1])
+	if i >= len(workflow.States) || workflow.States[i] == nil {
+		return ""
+	}
+	return workflow.States[i].GetName()
+}
+
+// workflowValidation collects the problems found in a workflow definition by the engine's own rules
+type workflowValidation struct {
+	parser   *Parser
+	workflow *sw.Workflow
+	// states are the indexes of the workflow states by name
+	states map[string]int
+	// functions are the declared functions by name, resolved to their activity, or nil when they are invalid
+	functions map[string]*Function
+	// expressions are the expressions of the expression functions by name
+	expressions map[string]string
+	errs        ValidationErrors
+}
+
+// add records a problem of the state at the JSON path. The state is nil for problems outside of the states.
+func (v *workflowValidation) add(state sw.State, path string, format string, args ...interface{}) {
+	err := &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	if state != nil {
+		err.State = state.GetName()
+	}
+	v.errs = append(v.errs, err)
+}
+
+// validateCustomRules performs the validation beyond what the SDK provides: the actions must call declared
+// functions that resolve to registered activities, transitions must lead to existing states, every state must be
+// reachable from the start and be able to reach an end, switch states need a default condition, and expressions
+// and durations must parse.
+func (p *Parser) validateCustomRules(workflow *sw.Workflow) *workflowValidation {
+	v := &workflowValidation{
+		parser:      p,
+		workflow:    workflow,
+		states:      make(map[string]int),
+		functions:   make(map[string]*Function),
+		expressions: ExpressionFunctions(workflow),
+	}
+	for i, state := range workflow.States {
+		if state != nil {
+			v.states[state.GetName()] = i
+		}
+	}
+
+	v.validateFunctions()
+
+	for i, state := range workflow.States {
+		if state == nil {
+			continue
+		}
+		path := fmt.Sprintf("states[%d]", i)
+		v.validateActions(state, path)
+		v.validateTransitions(state, path)
+		v.validateState(state, path)
+	}
+	for i, definition := range workflow.Errors {
+		v.validateExpression(nil, fmt.Sprintf("errors[%d].code", i), definition.Code, true)
+	}
+	v.validateFlow()
+
+	return v
+}
+
+// validateFunctions checks that every declared function resolves to a registered activity. The OpenAPI operations
+// of rest functions are loaded when a run first calls them, not here, so that parsing makes no requests.
+func (v *workflowValidation) validateFunctions() {
+	for i, function := range v.workflow.Functions {
+		path := fmt.Sprintf("functions[%d]", i)
+		if _, ok := v.functions[function.Name]; ok {
+			v.add(nil, path+".name", "function '%s' is declared more than once", function.Name)
+			continue
+		}
+
+		resolved, err := resolveActivity(v.parser.registry, function)
+		if err != nil {
+			v.add(nil, path+".operation", "%v", err)
+		} else if FunctionType(function) == sw.FunctionTypeExpression {
+			v.validateExpression(nil, path+".operation", function.Operation, false)
+		}
+		v.functions[function.Name] = resolved
+	}
+}
+
+// validateActions checks that the actions of the state call declared functions with valid arguments or name a
+// sub-flow, and that their expressions and sleep durations are valid
+func (v *workflowValidation) validateActions(state sw.State, statePath string) {
+	for _, action := range stateActions(state) {
+		path := fmt.Sprintf("%s.%s", statePath, action.path)
+
+		v.validateCondition(state, path+".condition", action.Condition)
+		if action.ActionDataFilter.FromStateData != "" {
+			v.validateExpression(state, path+".actionDataFilter.fromStateData", action.ActionDataFilter.FromStateData, false)
+		}
+		if action.ActionDataFilter.Results != "" {
+			v.validateExpression(state, path+".actionDataFilter.results", action.ActionDataFilter.Results, false)
+		}
+		if action.ActionDataFilter.ToStateData != "" {
+			v.validateExpression(state, path+".actionDataFilter.toStateData", action.ActionDataFilter.ToStateData, false)
+		}
+		v.validateDuration(state, path+".sleep.before", action.Sleep.Before)
+		v.validateDuration(state, path+".sleep.after", action.Sleep.After)
+
+		if action.SubFlowRef != nil {
+			if action.SubFlowRef.WorkflowID == "" {
+				v.add(state, path+".subFlowRef.workflowId", "state '%s' has a sub-flow action with missing workflow id", state.GetName())
+			}
+			continue
+		}
+		if action.FunctionRef == nil || action.FunctionRef.RefName == "" {
+			v.add(state, path, "state '%s' has an action with missing function reference", state.GetName())
+			continue
+		}
+
+		arguments, _ := objectValue(action.FunctionRef.Arguments).(map[string]interface{})
+		v.validateTemplates(state, path+".functionRef.arguments", arguments)
+
+		// Validate the referenced function is declared. Functions that do not resolve are reported once, with
+		// their declaration.
+		function, declared := v.functions[action.FunctionRef.RefName]
+		if !declared {
+			v.add(state, path+".functionRef.refName", "state '%s' references function '%s', which is not declared in functions", state.GetName(), action.FunctionRef.RefName)
+			continue
+		}
+		if function == nil || FunctionType(function.Function) != sw.FunctionTypeCustom {
+			continue
+		}
+		if declared, ok := v.parser.registry.GetArguments(function.Activity); ok {
+			if err := utils.ValidateStaticArgs(arguments, declared); err != nil {
+				v.add(state, path+".functionRef.arguments", "state '%s' has invalid arguments for activity '%s': %v", state.GetName(), function.Activity, err)
+			}
+		}
+	}
+}
+
+// transition is a way out of a state: either to the next state or to the end of the workflow
+type transition struct {
+	// path is the JSON path of the transition, e.g. "states[0].onErrors[1].transition"
+	path      string
+	nextState string
+	end       bool
+}
+
+// stateTransitions returns the ways out of a state, including error handlers and switch conditions
+func stateTransitions(state sw.State, statePath string) []transition {
+	var transitions []transition
+	appendTransition := func(path string, next *sw.Transition, end *sw.End) {
+		switch {
+		case next != nil:
+			transitions = append(transitions, transition{path: path + ".transition", nextState: next.NextState})
+		case end != nil:
+			transitions = append(transitions, transition{path: path + ".end", end: true})
+		}
+	}
+
+	appendTransition(statePath, state.GetTransition(), state.GetEnd())
+	for i, onError := range state.GetOnErrors() {
+		appendTransition(fmt.Sprintf("%s.onErrors[%d]", statePath, i), onError.Transition, onError.End)
+	}
+	if switchState, ok := state.(*sw.SwitchState); ok {
+		for i, condition := range switchState.DataConditions {
+			appendTransition(fmt.Sprintf("%s.dataConditions[%d]", statePath, i), condition.Transition, condition.End)
+		}
+		for i, condition := range switchState.EventConditions {
+			appendTransition(fmt.Sprintf("%s.eventConditions[%d]", statePath, i), condition.Transition, condition.End)
+		}
+		appendTransition(statePath+".defaultCondition", switchState.DefaultCondition.Transition, switchState.DefaultCondition.End)
+	}
+	return transitions
+}
+
+// validateTransitions checks that the transitions and the compensation of the state name existing states
+func (v *workflowValidation) validateTransitions(state sw.State, statePath string) {
+	for _, transition := range stateTransitions(state, statePath) {
+		if transition.end || transition.nextState == "" {
+			continue
+		}
+		if _, exists := v.states[transition.nextState]; !exists {
+			v.add(state, transition.path, "state '%s' transitions to state '%s', which does not exist", state.GetName(), transition.nextState)
+		}
+	}
+
+	if compensatedBy := state.GetCompensatedBy(); compensatedBy != "" {
+		if _, exists := v.states[compensatedBy]; !exists {
+			v.add(state, statePath+".compensatedBy", "state '%s' is compensated by state '%s', which does not exist", state.GetName(), compensatedBy)
+		}
+	}
+}
+
+// validateState checks the expressions, durations and conditions specific to the type of the state
+func (v *workflowValidation) validateState(state sw.State, statePath string) {
+	if filter := state.GetStateDataFilter(); filter != nil {
+		v.validateExpression(state, statePath+".stateDataFilter.input", filter.Input, false)
+		v.validateExpression(state, statePath+".stateDataFilter.output", filter.Output, false)
+	}
+	for i, onError := range state.GetOnErrors() {
+		v.validateExpression(state, fmt.Sprintf("%s.onErrors[%d].errorRef", statePath, i), onError.ErrorRef, true)
+	}
+
+	switch s := state.(type) {
+	case *sw.SwitchState:
+		if s.DefaultCondition.Transition == nil && s.DefaultCondition.End == nil {
+			v.add(state, statePath+".defaultCondition", "switch state '%s' has no default condition", s.Name)
+		}
+		for i, condition := range s.DataConditions {
+			v.validateCondition(state, fmt.Sprintf("%s.dataConditions[%d].condition", statePath, i), condition.Condition)
+		}
+		for i, condition := range s.EventConditions {
+			v.validateEventDataFilter(state, fmt.Sprintf("%s.eventConditions[%d].eventDataFilter", statePath, i), condition.EventDataFilter)
+		}
+	case *sw.SleepState:
+		v.validateDuration(state, statePath+".duration", s.Duration)
+	case *sw.InjectState:
+		v.validateTemplates(state, statePath+".data", objectValue(s.Data))
+	case *sw.ForEachState:
+		v.validateExpression(state, statePath+".inputCollection", s.InputCollection, false)
+		v.validateExpression(state, statePath+".outputCollection", s.OutputCollection, false)
+	case *sw.EventState:
+		for i, onEvents := range s.OnEvents {
+			v.validateEventDataFilter(state, fmt.Sprintf("%s.onEvents[%d].eventDataFilter", statePath, i), onEvents.EventDataFilter)
+		}
+	case *sw.CallbackState:
+		v.validateEventDataFilter(state, statePath+".eventDataFilter", s.EventDataFilter)
+	}
+}
+
+// validateEventDataFilter checks the expressions of an event data filter
+func (v *workflowValidation) validateEventDataFilter(state sw.State, path string, filter sw.EventDataFilter) {
+	v.validateExpression(state, path+".data", filter.Data, false)
+	v.validateExpression(state, path+".toStateData", filter.ToStateData, false)
+}
+
+// validateExpression checks that the expression parses as JQ. Expressions can be wrapped in ${ } or be bare JQ,
+// unless templateOnly is set, in which case only values wrapped in ${ } are expressions.
+func (v *workflowValidation) validateExpression(state sw.State, path string, expr string, templateOnly bool) {
+	if strings.TrimSpace(expr) == "" || (templateOnly && !utils.IsValidJQTemplate(expr)) {
+		return
+	}
+	if _, err := gojq.Parse(utils.TrimExpression(expr)); err != nil {
+		if state == nil {
+			v.add(nil, path, "invalid JQ expression '%s': %v", expr, err)
+			return
+		}
+		v.add(state, path, "state '%s' has an invalid JQ expression '%s': %v", state.GetName(), expr, err)
+	}
+}
+
+// validateCondition checks a condition, which can reference the workflow's expression functions, e.g. "fn:isAdult"
+func (v *workflowValidation) validateCondition(state sw.State, path string, expr string) {
+	if strings.TrimSpace(expr) == "" {
+		return
+	}
+	query, err := utils.ExpandFunctionReferences(expr, v.expressions)
+	if err == nil {
+		_, err = gojq.Parse(query)
+	}
+	if err != nil {
+		v.add(state, path, "state '%s' has an invalid JQ expression '%s': %v", state.GetName(), expr, err)
+	}
+}
+
+// validateTemplates checks the ${ } templates found anywhere in a value, such as the arguments of an action
+func (v *workflowValidation) validateTemplates(state sw.State, path string, value interface{}) {
+	switch value := value.(type) {
+	case string:
+		v.validateExpression(state, path, value, true)
+	case map[string]interface{}:
+		for key, item := range value {
+			v.validateTemplates(state, path+"."+key, item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			v.validateTemplates(state, fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	}
+}
+
+// validateDuration checks that a sleep duration is an ISO 8601 duration the engine can wait for
+func (v *workflowValidation) validateDuration(state sw.State, path string, duration string) {
+	if duration == "" {
+		return
+	}
+	if _, err := utils.ParseISODuration(duration); err != nil {
+		v.add(state, path, "state '%s' has an invalid sleep duration '%s': %v", state.GetName(), duration, err)
+	}
+}
+
+// validateFlow checks that every state is reachable from the start state and that every state can reach an end.
+// States used for compensation are reached through the compensatedBy of the states they compensate, and the
+// runBefore state of the workflow timeout is reached when the workflow times out.
+func (v *workflowValidation) validateFlow() {
+	states := v.workflow.States
+	if len(states) == 0 {
+		return
+	}
+
+	start := states[0]
+	if v.workflow.Start != nil {
+		i, exists := v.states[v.workflow.Start.StateName]
+		if !exists {
+			v.add(nil, "start", "start state '%s' does not exist", v.workflow.Start.StateName)
+			return
+		}
+		start = states[i]
+	}
+	if start == nil {
+		return
+	}
+
+	paths := make(map[string]string, len(states))
+	transitions := make(map[string][]transition, len(states))
+	for i, state := range states {
+		if state == nil {
+			continue
+		}
+		paths[state.GetName()] = fmt.Sprintf("states[%d]", i)
+		transitions[state.GetName()] = stateTransitions(state, paths[state.GetName()])
+	}
+
+	// Walk forward from the start state
+	reachable := map[string]bool{}
+	pending := []string{start.GetName()}
+	if timeouts := v.workflow.Timeouts; timeouts != nil && timeouts.WorkflowExecTimeout != nil && timeouts.WorkflowExecTimeout.RunBefore != "" {
+		pending = append(pending, timeouts.WorkflowExecTimeout.RunBefore)
+	}
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		i, exists := v.states[name]
+		if !exists || reachable[name] {
+			continue
+		}
+		reachable[name] = true
+		for _, transition := range transitions[name] {
+			if !transition.end {
+				pending = append(pending, transition.nextState)
+			}
+		}
+		if compensatedBy := states[i].GetCompensatedBy(); compensatedBy != "" {
+			pending = append(pending, compensatedBy)
+		}
+	}
+
+	// Walk backward from the ends until no more states can reach one
+	canEnd := map[string]bool{}
+	for changed := true; changed; {
+		changed = false
+		for name, stateTransitions := range transitions {
+			if canEnd[name] {
+				continue
+			}
+			for _, transition := range stateTransitions {
+				if transition.end || canEnd[transition.nextState] {
+					canEnd[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for _, state := range states {
+		if state == nil {
+			continue
+		}
+		name := state.GetName()
+		if !reachable[name] {
+			v.add(state, paths[name]+".name", "state '%s' is not reachable from the start state", name)
+		}
+		if !canEnd[name] {
+			v.add(state, paths[name]+".name", "state '%s' can never reach an end", name)
+		}
+	}
+}
+
+// objectValue converts SDK objects, such as action arguments, into plain JSON values
+func objectValue(object interface{}) interface{} {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return value
+}
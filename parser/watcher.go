@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// Change describes a workflow file that was added, modified or removed
+type Change struct {
+	// Path is the path of the workflow file
+	Path string
+	// Workflow is the parsed definition, or nil if the file was removed or is invalid
+	Workflow *Workflow
+	// Previous is the last accepted definition of the file, or nil if there was none
+	Previous *Workflow
+	// Err is the error the file failed to parse or validate with
+	Err error
+}
+
+// Watcher re-parses and re-validates the workflow files of a directory when they change. Changes are detected
+// by polling the files' modification times and sizes.
+type Watcher struct {
+	parser *Parser
+	dir    string
+	files  map[string]*watchedFile
+}
+
+// watchedFile is the last seen version of a workflow file
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+	// workflow is the last accepted definition of the file
+	workflow *Workflow
+}
+
+// NewWatcher creates a watcher for the workflow files in the directory and its subdirectories
+func (p *Parser) NewWatcher(dir string) *Watcher {
+	return &Watcher{
+		parser: p,
+		dir:    dir,
+		files:  make(map[string]*watchedFile),
+	}
+}
+
+// Load takes the initial snapshot of the directory. It parses every workflow file and passes the definitions to
+// register, in lexical order of their paths. The definitions become the accepted definitions of their files only
+// if register returns no error. A file that cannot be parsed fails the load without calling register.
+func (w *Watcher) Load(register func(workflows []*Workflow) error) error {
+	files := make(map[string]*watchedFile)
+	var workflows []*Workflow
+	err := filepath.WalkDir(w.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read workflow directory: %w", err)
+		}
+		if entry.IsDir() || !isWorkflowFile(path) {
+			return nil
+		}
+
+		// The file is stat'ed before it is read, so that a write in between is picked up by the next poll
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to read workflow directory: %w", err)
+		}
+		workflow, err := w.parser.ParseFromFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		files[path] = &watchedFile{modTime: info.ModTime(), size: info.Size(), workflow: workflow}
+		workflows = append(workflows, workflow)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := register(workflows); err != nil {
+		return err
+	}
+	w.files = files
+	return nil
+}
+
+// Poll scans the directory once and calls apply for every workflow file that was added, modified or removed
+// since the previous scan. Files that fail to parse are passed to apply with Err set. A definition becomes the
+// file's accepted definition, reported as Previous with the next change, only if apply returns no error.
+func (w *Watcher) Poll(apply func(change Change) error) error {
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(w.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !isWorkflowFile(path) {
+			return nil
+		}
+		seen[path] = true
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		file, ok := w.files[path]
+		if ok && file.modTime.Equal(info.ModTime()) && file.size == info.Size() {
+			return nil
+		}
+		if !ok {
+			file = &watchedFile{}
+			w.files[path] = file
+		}
+		file.modTime, file.size = info.ModTime(), info.Size()
+
+		change := Change{Path: path, Previous: file.workflow}
+		change.Workflow, change.Err = w.parser.ParseFromFile(path)
+		if change.Err != nil {
+			change.Workflow = nil
+			apply(change)
+			return nil
+		}
+		if apply(change) == nil {
+			file.workflow = change.Workflow
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read workflow directory: %w", err)
+	}
+
+	for path, file := range w.files {
+		if seen[path] {
+			continue
+		}
+		if file.workflow == nil || apply(Change{Path: path, Previous: file.workflow}) == nil {
+			delete(w.files, path)
+		}
+	}
+	return nil
+}
+
+// Watch polls the directory at the interval until the context is done
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration, apply func(change Change) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Poll(apply); err != nil {
+				apply(Change{Path: w.dir, Err: err})
+			}
+		}
+	}
+}
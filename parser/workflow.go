@@ -0,0 +1,17 @@
+package parser
+
+import sw "github.com/serverlessworkflow/sdk-go/v2/model"
+
+// Workflow is a workflow definition returned by the parser, together with the source it was parsed from.
+// Workflows decoded otherwise, e.g. directly with the SDK, can be run as &Workflow{Workflow: *workflow}, without the
+// defaults the parser fills in, e.g. useData of the event data filters a definition leaves out.
+type Workflow struct {
+	sw.Workflow
+	// Source is the JSON the workflow was parsed from, converted to JSON when it was YAML. Decoding a workflow and
+	// encoding it again loses data, e.g. the SDK drops "useResults": false, so this is the form to store it in. It
+	// is not updated when the workflow is changed after parsing: clear it then, and the workflow is encoded instead.
+	Source []byte
+	// Dir is the absolute directory of the file the workflow was parsed from, which relative paths in the
+	// definition, e.g. of schemas, are resolved against. It is empty for workflows not parsed from a file.
+	Dir string
+}
@@ -0,0 +1,311 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	sw "github.com/serverlessworkflow/sdk-go/v2/model"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlSyntaxError matches the line reported by YAML syntax errors, e.g. "yaml: line 3: did not find expected key"
+var yamlSyntaxError = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// pathSegment matches a segment of a JSON path, e.g. "actions[1]"
+var pathSegment = regexp.MustCompile(`^([^\[]*)((?:\[\d+\])*)$`)
+
+// ParseFromYAML parses a workflow from YAML bytes. Errors are reported at the line and column of the YAML
+// node that caused them, when it can be determined: syntax and type errors as a ParseError, and each problem of
+// ValidationErrors in its Line and Column.
+func (p *Parser) ParseFromYAML(data []byte) (*Workflow, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", yamlError(data, err))
+	}
+
+	var source interface{}
+	if err := document.Decode(&source); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", yamlError(data, err))
+	}
+	jsonSource, err := json.Marshal(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+
+	workflow := &sw.Workflow{}
+	if err := json.Unmarshal(jsonSource, workflow); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", locateError(&document, err))
+	}
+	if err := defaultEventDataFilters(workflow, jsonSource); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+
+	if err := p.validate(workflow); err != nil {
+		var errs ValidationErrors
+		if errors.As(err, &errs) {
+			locateValidationErrors(&document, errs)
+		}
+		return nil, err
+	}
+
+	return &Workflow{Workflow: *workflow, Source: jsonSource}, nil
+}
+
+// yamlError converts a YAML syntax error into a ParseError at the line it reports. YAML errors carry no column,
+// so the column is that of the first character on the line, where the node the error is about starts.
+func yamlError(data []byte, err error) error {
+	match := yamlSyntaxError.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(match[1])
+	parseErr := &ParseError{Line: line, Err: errors.New(match[2])}
+	if lines := bytes.Split(data, []byte("\n")); line > 0 && line <= len(lines) {
+		if i := bytes.IndexFunc(lines[line-1], func(r rune) bool { return !unicode.IsSpace(r) }); i >= 0 {
+			parseErr.Column = i + 1
+		}
+	}
+	return parseErr
+}
+
+// locateError wraps a type error in a ParseError at the position of the YAML node that caused it. Errors that
+// cannot be located are returned unchanged.
+func locateError(document *yaml.Node, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+
+	node := findMismatch(document, typeErr)
+	if node == nil || node.Line == 0 {
+		return err
+	}
+	return &ParseError{Line: node.Line, Column: node.Column, Err: err}
+}
+
+// locateValidationErrors sets the line and column of each problem to the position of the YAML node at its path
+func locateValidationErrors(document *yaml.Node, errs ValidationErrors) {
+	for _, err := range errs {
+		if node := findPath(document, err.Path); node != nil {
+			err.Line, err.Column = node.Line, node.Column
+		}
+	}
+}
+
+// findPath returns the node at the JSON path, e.g. "states[0].actions[1]", or the deepest node on the path
+// that exists. Path segments that are not keys of the current mapping, such as the names of embedded structs,
+// are skipped.
+func findPath(document *yaml.Node, path string) *yaml.Node {
+	node := root(document)
+	if path == "" {
+		return node
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		match := pathSegment.FindStringSubmatch(segment)
+		if match == nil {
+			return node
+		}
+		if match[1] != "" {
+			if value := mappingValue(node, match[1]); value != nil {
+				node = value
+			} else if match[2] != "" {
+				// An index into a field that does not exist
+				return node
+			}
+		}
+		for _, index := range strings.Split(strings.Trim(match[2], "[]"), "][") {
+			if index == "" {
+				continue
+			}
+			i, _ := strconv.Atoi(index)
+			node = resolveAlias(node)
+			if node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+				return node
+			}
+			node = node.Content[i]
+		}
+	}
+	return node
+}
+
+// findMismatch returns the node of the value a type error is about. The SDK decodes the workflow's own fields, and
+// then every state on its own, so the error's Field is the path from the object that was being decoded: the
+// workflow, or else the first state that fails to decode by itself. The value is the first one of the mismatched
+// type the path leads to from within it.
+func findMismatch(document *yaml.Node, typeErr *json.UnmarshalTypeError) *yaml.Node {
+	workflow := root(document)
+	scope := workflow
+	if !isTypeError(decodeNode(workflow, &sw.BaseWorkflow{})) {
+		scope = mismatchedState(workflow)
+		if scope == nil {
+			return nil
+		}
+	}
+
+	var path []string
+	if typeErr.Field != "" {
+		path = strings.Split(typeErr.Field, ".")
+	}
+	valueType, _, _ := strings.Cut(typeErr.Value, " ")
+
+	var find func(node *yaml.Node) *yaml.Node
+	find = func(node *yaml.Node) *yaml.Node {
+		node = resolveAlias(node)
+		if node.Kind != yaml.MappingNode && node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for _, value := range valuesAt(node, path) {
+			if jsonType(value) == valueType {
+				return value
+			}
+		}
+		for _, child := range node.Content {
+			if found := find(child); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return find(scope)
+}
+
+// mismatchedState returns the first state of the workflow that fails to decode with a type error
+func mismatchedState(workflow *yaml.Node) *yaml.Node {
+	states := mappingValue(workflow, "states")
+	if states == nil || states.Kind != yaml.SequenceNode {
+		return nil
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "states"}
+	for _, state := range states.Content {
+		// A workflow of just the state decodes it like the SDK decodes the states of the definition
+		single := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			key, {Kind: yaml.SequenceNode, Content: []*yaml.Node{state}},
+		}}
+		if isTypeError(decodeNode(single, &sw.Workflow{})) {
+			return state
+		}
+	}
+	return nil
+}
+
+// decodeNode decodes the node into v the way the parser decodes a definition: converted to JSON
+func decodeNode(node *yaml.Node, v interface{}) error {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// isTypeError reports whether the error is a JSON type error
+func isTypeError(err error) bool {
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &typeErr)
+}
+
+// valuesAt returns the values at the path of mapping keys from the node, following every element of the
+// sequences along it
+func valuesAt(node *yaml.Node, path []string) []*yaml.Node {
+	node = resolveAlias(node)
+	if len(path) == 0 {
+		return []*yaml.Node{node}
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		if value := mappingValue(node, path[0]); value != nil {
+			return valuesAt(value, path[1:])
+		}
+	case yaml.SequenceNode:
+		var values []*yaml.Node
+		for _, element := range node.Content {
+			values = append(values, valuesAt(element, path)...)
+		}
+		return values
+	}
+	return nil
+}
+
+// namespacePath converts a validator namespace, e.g. "Workflow.States[0].Actions", into a JSON path,
+// e.g. "states[0].actions"
+func namespacePath(namespace string) string {
+	segments := strings.Split(namespace, ".")
+	for i, segment := range segments {
+		name, indexes, _ := strings.Cut(segment, "[")
+		if indexes != "" {
+			indexes = "[" + indexes
+		}
+		segments[i] = jsonName(name) + indexes
+	}
+	// The first segment is the Workflow struct itself
+	return strings.Join(segments[1:], ".")
+}
+
+// jsonName returns the JSON name of a Go struct field: "ID" becomes "id" and "FunctionRef" becomes "functionRef"
+func jsonName(field string) string {
+	if strings.ToUpper(field) == field {
+		return strings.ToLower(field)
+	}
+	runes := []rune(field)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// root returns the top level node of a YAML document
+func root(document *yaml.Node) *yaml.Node {
+	if document.Kind == yaml.DocumentNode && len(document.Content) > 0 {
+		return document.Content[0]
+	}
+	return document
+}
+
+// mappingValue returns the value of the key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	node = resolveAlias(node)
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return resolveAlias(node.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// resolveAlias returns the node an alias refers to
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// jsonType returns the name JSON unmarshal errors use for the type of the YAML node
+func jsonType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.Tag {
+	case "!!int", "!!float":
+		return "number"
+	case "!!bool":
+		return "bool"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
@@ -0,0 +1,109 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/kshitiz1403/jsonjuggler/persistence"
+)
+
+// validRunID restricts run IDs to characters that are safe to use as file names
+var validRunID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Store keeps one JSON file per run in a directory. Files are replaced atomically, so a crash while
+// saving leaves the previous checkpoint intact.
+type Store struct {
+	dir string
+}
+
+// New creates a store in the directory, creating the directory if it does not exist
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory %s: %w", dir, err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save writes the checkpoint to the run's file
+func (s *Store) Save(ctx context.Context, checkpoint *persistence.Checkpoint) error {
+	path, err := s.path(checkpoint.RunID)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint of run %s: %w", checkpoint.RunID, err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, checkpoint.RunID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync checkpoint file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close checkpoint file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint file: %w", err)
+	}
+	return nil
+}
+
+// Load reads the checkpoint from the run's file
+func (s *Store) Load(ctx context.Context, runID string) (*persistence.Checkpoint, error) {
+	path, err := s.path(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, persistence.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+
+	var checkpoint persistence.Checkpoint
+	if err := json.Unmarshal(raw, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint of run %s: %w", runID, err)
+	}
+	return &checkpoint, nil
+}
+
+// Delete removes the run's file
+func (s *Store) Delete(ctx context.Context, runID string) error {
+	path, err := s.path(runID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete checkpoint file: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of the run
+func (s *Store) path(runID string) (string, error) {
+	if !validRunID.MatchString(runID) {
+		return "", fmt.Errorf("invalid run ID '%s'", runID)
+	}
+	return filepath.Join(s.dir, runID+".json"), nil
+}
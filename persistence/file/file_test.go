@@ -0,0 +1,78 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kshitiz1403/jsonjuggler/persistence"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "checkpoints")
+
+	store, err := New(dir)
+	require.NoError(t, err)
+
+	checkpoint := &persistence.Checkpoint{
+		RunID:      "run-1",
+		WorkflowID: "order-workflow",
+		Definition: []byte(`{"id":"order-workflow"}`),
+		Status:     persistence.StatusRunning,
+		NextState:  "Wait",
+		Current:    map[string]interface{}{"orderId": "A-1"},
+		States:     map[string]interface{}{"Reserve": map[string]interface{}{"reserved": true}},
+		Globals:    map[string]interface{}{},
+		UpdatedAt:  time.Now(),
+	}
+	require.NoError(t, store.Save(ctx, checkpoint))
+
+	// A new store on the same directory sees the checkpoint, as a restarted process would
+	reopened, err := New(dir)
+	require.NoError(t, err)
+
+	loaded, err := reopened.Load(ctx, "run-1")
+	require.NoError(t, err)
+	require.Equal(t, "Wait", loaded.NextState)
+	require.Equal(t, persistence.StatusRunning, loaded.Status)
+	require.Equal(t, checkpoint.Current, loaded.Current)
+	require.Equal(t, checkpoint.States, loaded.States)
+	require.JSONEq(t, `{"id":"order-workflow"}`, string(loaded.Definition))
+
+	// Saving again replaces the checkpoint without leaving temporary files behind
+	checkpoint.Status = persistence.StatusCompleted
+	checkpoint.NextState = ""
+	require.NoError(t, store.Save(ctx, checkpoint))
+	loaded, err = store.Load(ctx, "run-1")
+	require.NoError(t, err)
+	require.Equal(t, persistence.StatusCompleted, loaded.Status)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	require.NoError(t, store.Delete(ctx, "run-1"))
+	_, err = store.Load(ctx, "run-1")
+	require.ErrorIs(t, err, persistence.ErrNotFound)
+
+	// Deleting an unknown run is not an error
+	require.NoError(t, store.Delete(ctx, "run-1"))
+}
+
+func TestStoreRejectsInvalidRunIDs(t *testing.T) {
+	store, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	for _, runID := range []string{"", "../escape", "a/b", "run.json"} {
+		_, err := store.Load(context.Background(), runID)
+		require.Error(t, err, runID)
+		require.NotErrorIs(t, err, persistence.ErrNotFound, runID)
+
+		err = store.Save(context.Background(), &persistence.Checkpoint{RunID: runID})
+		require.Error(t, err, runID)
+	}
+}
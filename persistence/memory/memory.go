@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/kshitiz1403/jsonjuggler/persistence"
+)
+
+// Store keeps checkpoints in memory. Checkpoints are stored serialized, so a loaded checkpoint never
+// shares data with the running workflow. It is meant for tests and single process deployments.
+type Store struct {
+	mu          sync.RWMutex
+	checkpoints map[string][]byte
+}
+
+// New creates an empty in-memory store
+func New() *Store {
+	return &Store{
+		checkpoints: make(map[string][]byte),
+	}
+}
+
+// Save stores the checkpoint
+func (s *Store) Save(ctx context.Context, checkpoint *persistence.Checkpoint) error {
+	raw, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint of run %s: %w", checkpoint.RunID, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[checkpoint.RunID] = raw
+	return nil
+}
+
+// Load returns the latest checkpoint of the run
+func (s *Store) Load(ctx context.Context, runID string) (*persistence.Checkpoint, error) {
+	s.mu.RLock()
+	raw, ok := s.checkpoints[runID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, persistence.ErrNotFound
+	}
+
+	var checkpoint persistence.Checkpoint
+	if err := json.Unmarshal(raw, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint of run %s: %w", runID, err)
+	}
+	return &checkpoint, nil
+}
+
+// Delete removes the checkpoint of the run
+func (s *Store) Delete(ctx context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.checkpoints, runID)
+	return nil
+}
@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kshitiz1403/jsonjuggler/persistence"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+
+	current := map[string]interface{}{"count": 1}
+	require.NoError(t, store.Save(ctx, &persistence.Checkpoint{
+		RunID:     "run-1",
+		Status:    persistence.StatusRunning,
+		NextState: "Next",
+		Current:   current,
+	}))
+
+	// Changes to the workflow data after saving do not leak into the stored checkpoint
+	current["count"] = 2
+
+	loaded, err := store.Load(ctx, "run-1")
+	require.NoError(t, err)
+	require.Equal(t, "Next", loaded.NextState)
+	require.Equal(t, map[string]interface{}{"count": 1.0}, loaded.Current)
+
+	require.NoError(t, store.Delete(ctx, "run-1"))
+	_, err = store.Load(ctx, "run-1")
+	require.ErrorIs(t, err, persistence.ErrNotFound)
+}
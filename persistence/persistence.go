@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by a Store when no checkpoint exists for a run
+var ErrNotFound = errors.New("checkpoint not found")
+
+// Status is the status of a workflow run
+type Status string
+
+const (
+	// StatusRunning means the run has not finished yet and can be resumed from NextState
+	StatusRunning Status = "running"
+	// StatusCompleted means the run reached an end state
+	StatusCompleted Status = "completed"
+	// StatusFailed means the run stopped with an error while executing NextState
+	StatusFailed Status = "failed"
+)
+
+// Checkpoint is the persisted progress of a workflow run
+type Checkpoint struct {
+	// RunID identifies the workflow run
+	RunID string `json:"runId"`
+	// WorkflowID is the ID of the workflow being run
+	WorkflowID string `json:"workflowId"`
+	// WorkflowVersion is the version of the workflow being run
+	WorkflowVersion string `json:"workflowVersion,omitempty"`
+	// Definition is the JSON definition of the workflow, so a run can be resumed by another process
+	Definition json.RawMessage `json:"definition"`
+	// DefinitionDir is the directory of the file the definition was parsed from, which its relative paths resolve against
+	DefinitionDir string `json:"definitionDir,omitempty"`
+	// Status is the status of the run
+	Status Status `json:"status"`
+	// NextState is the state the run continues with when it is resumed
+	NextState string `json:"nextState,omitempty"`
+	// Error is the error the run failed with
+	Error string `json:"error,omitempty"`
+
+	// Initial, Current, States and Globals are the workflow data of the run
+	Initial interface{}            `json:"initial"`
+	Current interface{}            `json:"current"`
+	States  map[string]interface{} `json:"states"`
+	Globals map[string]interface{} `json:"globals"`
+	// CaughtError is the error caught by onErrors that the next state sees at .error
+	CaughtError map[string]interface{} `json:"caughtError,omitempty"`
+	// Compensable lists the completed states that are compensated if the run fails or compensates
+	Compensable []string `json:"compensable,omitempty"`
+	// Callback is set while the run waits in the callback state NextState, after it performed the state's action
+	Callback *Callback `json:"callback,omitempty"`
+
+	// UpdatedAt is the time the checkpoint was saved
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Callback is a callback state a run waits in for its callback event
+type Callback struct {
+	// State is the name of the callback state
+	State string `json:"state"`
+	// Data is the state data after the state's action, which the callback event is merged into
+	Data interface{} `json:"data"`
+}
+
+// Store persists checkpoints of workflow runs. Implementations must be safe for concurrent use.
+type Store interface {
+	// Save stores the checkpoint, replacing any previous checkpoint of the same run
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+	// Load returns the latest checkpoint of the run, or ErrNotFound
+	Load(ctx context.Context, runID string) (*Checkpoint, error)
+	// Delete removes the checkpoint of the run. Deleting an unknown run is not an error.
+	Delete(ctx context.Context, runID string) error
+}
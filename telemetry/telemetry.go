@@ -3,6 +3,7 @@ package telemetry
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -36,6 +37,7 @@ type Telemetry struct {
 	activityErrorCount    metric.Int64Counter
 	workflowStateCount    metric.Int64Counter
 	workflowActivityCount metric.Int64Counter
+	activityRetryCount    metric.Int64Counter
 
 	enabled bool
 }
@@ -98,6 +100,12 @@ func New(cfg *Config) (*Telemetry, error) {
 		return nil, fmt.Errorf("failed to create workflow activity counter: %w", err)
 	}
 
+	activityRetryCount, err := meter.Int64Counter("activity.retries",
+		metric.WithDescription("Number of activity retry attempts"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create activity retry counter: %w", err)
+	}
+
 	return &Telemetry{
 		tracer:                tp.Tracer(serviceName),
 		meter:                 meter,
@@ -107,6 +115,7 @@ func New(cfg *Config) (*Telemetry, error) {
 		activityErrorCount:    activityErrorCount,
 		workflowStateCount:    workflowStateCount,
 		workflowActivityCount: workflowActivityCount,
+		activityRetryCount:    activityRetryCount,
 		enabled:               true,
 	}, nil
 }
@@ -230,6 +239,36 @@ func (t *Telemetry) StartActionGroupSpan(ctx context.Context, stateName string,
 	return ctx, span
 }
 
+// StartBranchSpan starts a new span for a single branch of a parallel state
+func (t *Telemetry) StartBranchSpan(ctx context.Context, stateName, branchName string) (context.Context, trace.Span) {
+	if !t.enabled {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	ctx, span := t.tracer.Start(ctx, "workflow.parallel.branch",
+		trace.WithAttributes(
+			attribute.String("state.name", stateName),
+			attribute.String("branch.name", branchName),
+		))
+
+	return ctx, span
+}
+
+// StartIterationSpan starts a new span for a single iteration of a foreach state
+func (t *Telemetry) StartIterationSpan(ctx context.Context, stateName string, index int) (context.Context, trace.Span) {
+	if !t.enabled {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	ctx, span := t.tracer.Start(ctx, "workflow.foreach.iteration",
+		trace.WithAttributes(
+			attribute.String("state.name", stateName),
+			attribute.Int("iteration.index", index),
+		))
+
+	return ctx, span
+}
+
 // StartErrorHandlingSpan starts a new span for error handling
 func (t *Telemetry) StartErrorHandlingSpan(ctx context.Context, stateName, errorType, errString, handlerAction string) (context.Context, trace.Span) {
 	if !t.enabled {
@@ -262,6 +301,52 @@ func (t *Telemetry) StartSleepSpan(ctx context.Context, stateName, duration stri
 	return ctx, span
 }
 
+// StartEventWaitSpan starts a new span for waiting on events
+func (t *Telemetry) StartEventWaitSpan(ctx context.Context, stateName string, eventRefs []string) (context.Context, trace.Span) {
+	if !t.enabled {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	ctx, span := t.tracer.Start(ctx, "workflow.event.wait",
+		trace.WithAttributes(
+			attribute.String("state.name", stateName),
+			attribute.StringSlice("event.refs", eventRefs),
+		))
+
+	return ctx, span
+}
+
+// StartSubFlowSpan starts a new span for a sub-flow action
+func (t *Telemetry) StartSubFlowSpan(ctx context.Context, workflowID, version, invoke string) (context.Context, trace.Span) {
+	if !t.enabled {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	ctx, span := t.tracer.Start(ctx, "workflow.subflow",
+		trace.WithAttributes(
+			attribute.String("subflow.workflow.id", workflowID),
+			attribute.String("subflow.version", version),
+			attribute.String("subflow.invoke", invoke),
+		))
+
+	return ctx, span
+}
+
+// StartCompensationSpan starts a new span for the compensation of a state
+func (t *Telemetry) StartCompensationSpan(ctx context.Context, stateName, compensatedBy string) (context.Context, trace.Span) {
+	if !t.enabled {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	ctx, span := t.tracer.Start(ctx, "workflow.compensation",
+		trace.WithAttributes(
+			attribute.String("state.name", stateName),
+			attribute.String("compensation.state", compensatedBy),
+		))
+
+	return ctx, span
+}
+
 // RecordWorkflowDuration records workflow execution duration
 func (t *Telemetry) RecordWorkflowDuration(ctx context.Context, duration float64, workflowID string) {
 	if !t.enabled {
@@ -336,3 +421,37 @@ func (t *Telemetry) RecordActivity(ctx context.Context, activityName string) {
 			attribute.String("activity.name", activityName),
 		))
 }
+
+// RecordActivityRetry records a failed activity attempt that is going to be retried.
+// The attempt is added as an event to the current span and counted in the retry metric.
+func (t *Telemetry) RecordActivityRetry(ctx context.Context, activityName string, attempt int, delay time.Duration, err error) {
+	if !t.enabled {
+		return
+	}
+
+	trace.SpanFromContext(ctx).AddEvent("activity.retry",
+		trace.WithAttributes(
+			attribute.String("activity.name", activityName),
+			attribute.Int("retry.attempt", attempt),
+			attribute.String("retry.delay", delay.String()),
+			attribute.String("error.string", err.Error()),
+		))
+
+	t.activityRetryCount.Add(ctx, 1,
+		metric.WithAttributes(
+			attribute.String("activity.name", activityName),
+		))
+}
+
+// RecordEventConsumed records an event consumed by a state as an event on the current span
+func (t *Telemetry) RecordEventConsumed(ctx context.Context, eventRef, eventType string) {
+	if !t.enabled {
+		return
+	}
+
+	trace.SpanFromContext(ctx).AddEvent("event.consumed",
+		trace.WithAttributes(
+			attribute.String("event.ref", eventRef),
+			attribute.String("event.type", eventType),
+		))
+}
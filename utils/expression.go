@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// EvaluateExpression evaluates a workflow expression against data and returns its first result.
+// The expression can either be wrapped in ${ } or be a bare JQ query.
+func EvaluateExpression(expr string, data interface{}) (interface{}, error) {
+	query := TrimExpression(expr)
+
+	q, err := gojq.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JQ query '%s': %w", query, err)
+	}
+
+	iter := q.Run(data)
+	result, ok := iter.Next()
+	if !ok {
+		return nil, fmt.Errorf("no result for JQ query '%s'", query)
+	}
+	if err, ok := result.(error); ok {
+		return nil, fmt.Errorf("JQ query '%s' failed: %w", query, err)
+	}
+
+	return result, nil
+}
+
+// EvaluateExpressionWithArgs evaluates a workflow expression against data like EvaluateExpression, with the
+// arguments bound to the $args variable
+func EvaluateExpressionWithArgs(expr string, data interface{}, args map[string]interface{}) (interface{}, error) {
+	query := TrimExpression(expr)
+
+	q, err := gojq.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JQ query '%s': %w", query, err)
+	}
+
+	code, err := gojq.Compile(q, gojq.WithVariables([]string{"$args"}))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JQ query '%s': %w", query, err)
+	}
+
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	normalizedArgs, err := NormalizeJSON(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize arguments for JQ query '%s': %w", query, err)
+	}
+	normalizedData, err := NormalizeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize data for JQ query '%s': %w", query, err)
+	}
+
+	iter := code.Run(normalizedData, normalizedArgs)
+	result, ok := iter.Next()
+	if !ok {
+		return nil, fmt.Errorf("no result for JQ query '%s'", query)
+	}
+	if err, ok := result.(error); ok {
+		return nil, fmt.Errorf("JQ query '%s' failed: %w", query, err)
+	}
+
+	return result, nil
+}
+
+// ExpandFunctionReferences rewrites the references to expression functions in an expression, e.g.
+// "${ fn:isAdult and .current.active }", into a bare JQ query that defines the referenced functions. The functions
+// map the names of expression functions to their expressions. A referenced function is evaluated against the
+// state data, .current, wherever it appears, with an empty $args.
+func ExpandFunctionReferences(expr string, functions map[string]string) (string, error) {
+	query := TrimExpression(expr)
+
+	var names []string
+	var missing error
+	query = replaceFunctionReferences(query, func(name string) string {
+		if _, ok := functions[name]; !ok {
+			if missing == nil {
+				missing = fmt.Errorf("expression function '%s' is not declared", name)
+			}
+			return "fn:" + name
+		}
+		names = append(names, name)
+		return "_fn_" + name
+	})
+	if missing != nil {
+		return "", missing
+	}
+	if len(names) == 0 {
+		return query, nil
+	}
+
+	sort.Strings(names)
+	var definitions strings.Builder
+	definitions.WriteString(". as $_data | ")
+	for i, name := range names {
+		if i > 0 && names[i-1] == name {
+			continue
+		}
+		fmt.Fprintf(&definitions, "def _fn_%s: $_data.current | {} as $args | (%s); ", name, TrimExpression(functions[name]))
+	}
+	return definitions.String() + query, nil
+}
+
+// replaceFunctionReferences replaces the references to expression functions in a JQ query, e.g. "fn:isAdult", with
+// what replace returns for the function name. References in string literals are text, e.g. in .id == "fn:abc",
+// except in their \( ) interpolations, which are part of the query again.
+func replaceFunctionReferences(query string, replace func(name string) string) string {
+	var result strings.Builder
+	inString := false
+	// interpolations holds the number of parentheses open in each string interpolation the scanner is in
+	var interpolations []int
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		if inString {
+			switch {
+			case c == '\\' && strings.HasPrefix(query[i+1:], "("):
+				interpolations = append(interpolations, 0)
+				inString = false
+				result.WriteString(query[i : i+2])
+				i += 2
+				continue
+			case c == '\\' && i+1 < len(query):
+				result.WriteString(query[i : i+2])
+				i += 2
+				continue
+			case c == '"':
+				inString = false
+			}
+			result.WriteByte(c)
+			i++
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '(':
+			if n := len(interpolations); n > 0 {
+				interpolations[n-1]++
+			}
+		case ')':
+			if n := len(interpolations); n > 0 {
+				if interpolations[n-1] == 0 {
+					interpolations = interpolations[:n-1]
+					inString = true
+				} else {
+					interpolations[n-1]--
+				}
+			}
+		case 'f':
+			if strings.HasPrefix(query[i:], "fn:") && (i == 0 || !isIdentifierByte(query[i-1])) {
+				end := i + len("fn:")
+				for end < len(query) && isIdentifierByte(query[end]) && (end > i+len("fn:") || !isDigit(query[end])) {
+					end++
+				}
+				if end > i+len("fn:") {
+					result.WriteString(replace(query[i+len("fn:") : end]))
+					i = end
+					continue
+				}
+			}
+		}
+		result.WriteByte(c)
+		i++
+	}
+	return result.String()
+}
+
+// isIdentifierByte reports whether the byte can be part of a JQ identifier
+func isIdentifierByte(c byte) bool {
+	return c == '_' || isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// isDigit reports whether the byte is an ASCII digit
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// SetExpressionPath returns a copy of data with value set at the location selected by the path expression,
+// e.g. "${ .results }" or ".order.items[0]". Missing objects along the path are created.
+func SetExpressionPath(data interface{}, pathExpr string, value interface{}) (interface{}, error) {
+	path := TrimExpression(pathExpr)
+
+	q, err := gojq.Parse(fmt.Sprintf("setpath(path(%s); $value)", path))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JQ path '%s': %w", path, err)
+	}
+
+	code, err := gojq.Compile(q, gojq.WithVariables([]string{"$value"}))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JQ path '%s': %w", path, err)
+	}
+
+	normalized, err := NormalizeJSON(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize value for path '%s': %w", path, err)
+	}
+
+	iter := code.Run(data, normalized)
+	result, ok := iter.Next()
+	if !ok {
+		return nil, fmt.Errorf("no result for JQ path '%s'", path)
+	}
+	if err, ok := result.(error); ok {
+		return nil, fmt.Errorf("JQ path '%s' failed: %w", path, err)
+	}
+
+	return result, nil
+}
+
+// TrimExpression strips the ${ } wrapper from an expression if present
+func TrimExpression(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if IsValidJQTemplate(expr) {
+		query, _ := ExtractJQTemplate(expr)
+		return strings.TrimSpace(query)
+	}
+	return expr
+}
+
+// NormalizeJSON converts a value into the plain JSON types, e.g. float64 for every number, understood by JQ and
+// the JSON Schema validator
+func NormalizeJSON(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
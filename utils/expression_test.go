@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateExpression(t *testing.T) {
+	data := map[string]interface{}{
+		"current": map[string]interface{}{
+			"items": []interface{}{"a", "b"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		expr      string
+		want      interface{}
+		wantError bool
+	}{
+		{
+			name: "templated expression",
+			expr: "${ .current.items }",
+			want: []interface{}{"a", "b"},
+		},
+		{
+			name: "bare expression",
+			expr: ".current.items | length",
+			want: 2,
+		},
+		{
+			name:      "invalid expression",
+			expr:      "${ .current.[ }",
+			wantError: true,
+		},
+		{
+			name:      "runtime error",
+			expr:      `error("boom")`,
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvaluateExpression(tt.expr, data)
+			if tt.wantError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvaluateExpressionWithArgs(t *testing.T) {
+	data := map[string]interface{}{"price": 10}
+
+	got, err := EvaluateExpressionWithArgs("${ .price * $args.quantity }", data, map[string]interface{}{"quantity": 3})
+	require.NoError(t, err)
+	require.Equal(t, float64(30), got)
+
+	// Without arguments $args is an empty object
+	got, err = EvaluateExpressionWithArgs("$args.quantity // 1", data, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, got)
+
+	_, err = EvaluateExpressionWithArgs("$quantity", data, nil)
+	require.Error(t, err)
+}
+
+func TestExpandFunctionReferences(t *testing.T) {
+	functions := map[string]string{
+		"isAdult": ".age >= 18",
+		"isVIP":   "${ .tier == \"gold\" }",
+	}
+	data := map[string]interface{}{
+		"current": map[string]interface{}{"age": 30, "tier": "gold", "tags": []interface{}{"a"}},
+	}
+
+	tests := []struct {
+		name      string
+		expr      string
+		want      interface{}
+		wantError string
+	}{
+		{
+			name: "function reference",
+			expr: "${ fn:isAdult }",
+			want: true,
+		},
+		{
+			name: "functions evaluate against the state data wherever they are referenced",
+			expr: ".current.tags | map(fn:isAdult and fn:isVIP)",
+			want: []interface{}{true},
+		},
+		{
+			name: "references in string literals are text",
+			expr: `${ "fn:isMinor \"fn:isMinor\"" | startswith("fn:") and fn:isAdult }`,
+			want: true,
+		},
+		{
+			name: "references in string interpolations are expanded",
+			expr: `${ "adult: \(fn:isAdult), vip: \((fn:isVIP))" }`,
+			want: "adult: true, vip: true",
+		},
+		{
+			name: "no references",
+			expr: "${ .current.age }",
+			want: 30,
+		},
+		{
+			name:      "undeclared function",
+			expr:      "fn:isMinor",
+			wantError: "expression function 'isMinor' is not declared",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ExpandFunctionReferences(tt.expr, functions)
+			if tt.wantError != "" {
+				require.ErrorContains(t, err, tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := EvaluateExpression(query, data)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSetExpressionPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  interface{}
+		path  string
+		value interface{}
+		want  interface{}
+	}{
+		{
+			name:  "set top level key",
+			data:  map[string]interface{}{"id": "1"},
+			path:  "${ .results }",
+			value: []interface{}{"x"},
+			want:  map[string]interface{}{"id": "1", "results": []interface{}{"x"}},
+		},
+		{
+			name:  "create nested objects",
+			data:  nil,
+			path:  ".order.status",
+			value: "done",
+			want:  map[string]interface{}{"order": map[string]interface{}{"status": "done"}},
+		},
+		{
+			name:  "normalize go types",
+			data:  map[string]interface{}{},
+			path:  ".headers",
+			value: map[string]string{"a": "b"},
+			want:  map[string]interface{}{"headers": map[string]interface{}{"a": "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetExpressionPath(tt.data, tt.path, tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -29,6 +29,49 @@ Usage:
 		}
 */
 func ParseAndValidateArgs(ctx context.Context, args map[string]any, dest interface{}) error {
+	return parseAndValidateArgs(args, dest, nil)
+}
+
+// ValidateStaticArgs checks the arguments of an action before they are evaluated, the way ParseAndValidateArgs
+// checks them at runtime. Arguments that are or contain ${ } templates are only known at runtime: they satisfy
+// required fields, but their types and validation tags are not checked.
+func ValidateStaticArgs(args map[string]any, dest interface{}) error {
+	static := make(map[string]any, len(args))
+	templates := make(map[string]bool)
+	for name, value := range args {
+		if containsTemplates(value) {
+			templates[name] = true
+			continue
+		}
+		static[name] = value
+	}
+	return parseAndValidateArgs(static, dest, templates)
+}
+
+// containsTemplates reports whether the value is a ${ } template or has one in its maps and lists
+func containsTemplates(value any) bool {
+	switch value := value.(type) {
+	case string:
+		return IsValidJQTemplate(value)
+	case map[string]any:
+		for _, item := range value {
+			if containsTemplates(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range value {
+			if containsTemplates(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseAndValidateArgs parses and validates the arguments into dest. Deferred arguments are present but have no
+// value yet, so they only count towards required fields.
+func parseAndValidateArgs(args map[string]any, dest interface{}, deferred map[string]bool) error {
 	// Configure mapstructure decoder
 	config := &mapstructure.DecoderConfig{
 		TagName:          "arg",
@@ -62,7 +105,7 @@ func ParseAndValidateArgs(ctx context.Context, args map[string]any, dest interfa
 		}
 
 		if field.Tag.Get("required") == "true" {
-			if _, ok := args[argName]; !ok {
+			if _, ok := args[argName]; !ok && !deferred[argName] {
 				return fmt.Errorf("required argument '%s' is missing", argName)
 			}
 		}
@@ -85,7 +128,7 @@ func ParseAndValidateArgs(ctx context.Context, args map[string]any, dest interfa
 		argName := field.Tag.Get("arg")
 		validateTag := field.Tag.Get("validate")
 
-		if validateTag == "" {
+		if validateTag == "" || deferred[argName] {
 			continue
 		}
 
@@ -208,3 +208,106 @@ func TestValidateArgsRequiredVsValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateStaticArgs(t *testing.T) {
+	type testStruct struct {
+		URL        string            `arg:"url" required:"true"`
+		Method     string            `arg:"method" required:"true" validate:"oneof=GET POST"`
+		Headers    map[string]string `arg:"headers"`
+		TimeoutSec int               `arg:"timeoutSec" validate:"min=1,max=300"`
+		Recipients []string          `arg:"recipients" validate:"min=2"`
+	}
+
+	tests := []struct {
+		name      string
+		args      map[string]any
+		wantError bool
+		errorMsg  string
+	}{
+		{
+			name: "static arguments",
+			args: map[string]any{
+				"url":    "https://example.com",
+				"method": "GET",
+			},
+			wantError: false,
+		},
+		{
+			name: "templates satisfy required fields",
+			args: map[string]any{
+				"url":        "${ .current.url }",
+				"method":     "${ .current.method }",
+				"timeoutSec": "${ .current.timeout }",
+			},
+			wantError: false,
+		},
+		{
+			name: "nested templates are not decoded",
+			args: map[string]any{
+				"url":     "https://example.com",
+				"method":  "POST",
+				"headers": map[string]any{"Authorization": "${ .current.token }", "Accept": "application/json"},
+			},
+			wantError: false,
+		},
+		{
+			name: "lists with templates are not validated",
+			args: map[string]any{
+				"url":        "https://example.com",
+				"method":     "POST",
+				"recipients": []any{"${ .current.email }", "ops@example.com"},
+			},
+			wantError: false,
+		},
+		{
+			name: "invalid static list",
+			args: map[string]any{
+				"url":        "https://example.com",
+				"method":     "POST",
+				"recipients": []any{"ops@example.com"},
+			},
+			wantError: true,
+			errorMsg:  "validation failed for field 'Recipients'",
+		},
+		{
+			name: "missing required field",
+			args: map[string]any{
+				"method": "${ .current.method }",
+			},
+			wantError: true,
+			errorMsg:  "required argument 'url' is missing",
+		},
+		{
+			name: "invalid static value",
+			args: map[string]any{
+				"url":    "${ .current.url }",
+				"method": "DELETE",
+			},
+			wantError: true,
+			errorMsg:  "validation failed for field 'Method'",
+		},
+		{
+			name: "wrong static type",
+			args: map[string]any{
+				"url":     "https://example.com",
+				"method":  "GET",
+				"headers": []any{"Accept"},
+			},
+			wantError: true,
+			errorMsg:  "failed to decode arguments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s testStruct
+			err := ValidateStaticArgs(tt.args, &s)
+
+			if tt.wantError {
+				require.ErrorContains(t, err, tt.errorMsg)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}